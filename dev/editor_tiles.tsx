@@ -1,4 +1,4 @@
 <?xml version="1.0" encoding="UTF-8"?>
-<tileset version="1.2" tiledversion="1.3.5" name="editor_tiles" tilewidth="16" tileheight="16" tilecount="16" columns="8">
- <image source="editor_tiles.png" width="128" height="32"/>
+<tileset version="1.2" tiledversion="1.3.5" name="editor_tiles" tilewidth="16" tileheight="16" tilecount="40" columns="8">
+ <image source="editor_tiles.png" width="128" height="80"/>
 </tileset>
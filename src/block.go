@@ -2,14 +2,11 @@ package main
 
 import (
 	"github.com/jani-nykanen/blocked/src/core"
+	"github.com/jani-nykanen/blocked/src/puzzle"
 )
 
 const (
 	blockMoveTime = 8
-
-	blockNoHole    = 0
-	blockRightHole = 1
-	blockWrongHole = 2 // ehehehhehehe
 )
 
 type block struct {
@@ -23,141 +20,52 @@ type block struct {
 	moving      bool
 	moveTimer   int32
 	jumping     bool
-	deactivated bool
-	playDestroy bool
-	playHit     bool
-}
-
-func (b *block) handleControls(s *stage, ev *core.Event) bool {
-
-	if !b.exist || b.moving {
-
-		return false
-	}
-
-	dx := int32(0)
-	dy := int32(0)
-	if ev.Input.GetActionState("left")&core.StateDownOrPressed == 1 {
-
-		dx = -1
-
-	} else if ev.Input.GetActionState("right")&core.StateDownOrPressed == 1 {
-
-		dx = 1
-
-	} else if ev.Input.GetActionState("up")&core.StateDownOrPressed == 1 {
-
-		dy = -1
-
-	} else if ev.Input.GetActionState("down")&core.StateDownOrPressed == 1 {
-
-		dy = 1
-	}
-
-	if dx != 0 || dy != 0 {
-
-		b.moveTo(dx, dy, s, false)
-
-		return b.moving
-	}
-
-	return false
+	teleporting bool
 }
 
-func (b *block) moveTo(dx, dy int32, s *stage, wasMoving bool) {
-
-	if b.moving || s.getSolid(b.pos.X+dx, b.pos.Y+dy) != 0 {
-
-		if wasMoving {
+func (b *block) moveTo(m puzzle.Move) {
 
-			b.playHit = true
-		}
+	b.pos = core.NewPoint(m.From.X, m.From.Y)
+	b.target = core.NewPoint(m.To.X, m.To.Y)
+	b.dir = core.NewPoint(m.Dir.X, m.Dir.Y)
 
-		return
-	}
-
-	b.dir.X = dx
-	b.dir.Y = dy
-
-	b.jumping = b.pos.X+dx < 0 || b.pos.X+dx >= s.width ||
-		b.pos.Y+dy < 0 || b.pos.Y+dy >= s.height
+	b.jumping = m.Wrapped
+	b.teleporting = m.Teleported
 
 	b.moveTimer += blockMoveTime
 	b.moving = true
-
-	b.target.X = core.NegMod(b.pos.X+dx, s.width)
-	b.target.Y = core.NegMod(b.pos.Y+dy, s.height)
-
-	s.updateSolidTile(b.pos.X, b.pos.Y, 0)
 }
 
-func (b *block) handleMovement(s *stage, ev *core.Event) int32 {
+// Returns true if the block reached its target
+func (b *block) handleMovement(ev *core.Event) bool {
 
-	if !b.moving {
-		return blockNoHole
+	if !b.exist || !b.moving {
+		return false
 	}
 
-	var hitHole, correctHole bool
-
 	b.moveTimer -= ev.Step()
 	if b.moveTimer <= 0 {
 
 		b.pos = b.target
-
-		// Check if hits a hole
-		if b.id != 0 {
-
-			hitHole, correctHole = s.checkHoleTile(b.pos.X, b.pos.Y, b.id-1)
-			if hitHole {
-
-				if correctHole {
-
-					b.exist = false
-					b.moving = false
-					b.moveTimer = 0
-
-					b.deactivated = false
-
-					s.updateSolidTile(b.pos.X, b.pos.Y, 2)
-
-					b.playDestroy = true
-
-					return blockRightHole
-				}
-
-				ev.Audio.PlaySample(ev.Assets.GetAsset("failure").(*core.Sample),
-					60)
-
-				return blockWrongHole
-			}
-		}
-
-		// Keep moving to the same direction, if possible
 		b.moving = false
-		b.moveTo(b.dir.X, b.dir.Y, s, true)
-
-		if !b.moving {
 
-			b.moveTimer = 0
-			s.updateSolidTile(b.pos.X, b.pos.Y, 2)
-		}
+		return true
 	}
 
-	return blockNoHole
+	return false
 }
 
-func (b *block) safeCheck(s *stage) {
+func (b *block) paint(id int32) {
 
-	// Sometimes this ugly thing happen
-	if s.getSolid(b.target.X, b.target.Y) != 0 {
+	b.id = id
+	b.spr.SetFrame(id, b.spr.Row())
+}
 
-		b.moveTimer = 0
-		b.moving = false
-		b.target = b.pos
+func (b *block) stop() {
 
-		s.updateSolidTile(b.pos.X, b.pos.Y, 2)
-		b.computeRenderingPosition()
-	}
+	b.moveTimer = 0
+	b.jumping = false
+	b.teleporting = false
 }
 
 func (b *block) computeRenderingPosition() {
@@ -176,7 +84,7 @@ func (b *block) computeRenderingPosition() {
 
 		t = float32(b.moveTimer) / float32(blockMoveTime)
 
-		if b.jumping {
+		if b.jumping || b.teleporting {
 
 			target.X = b.pos.X + b.dir.X
 			target.Y = b.pos.Y + b.dir.Y
@@ -190,51 +98,36 @@ func (b *block) computeRenderingPosition() {
 	}
 }
 
-func (b *block) update(anyMoving bool, s *stage, ev *core.Event) int32 {
-
-	b.playDestroy = false
-	b.playHit = false
-
-	if !b.exist {
-
-		if !b.deactivated && !anyMoving {
+// When going through the edge of the stage or a
+// teleporter, a second copy of the block is drawn
+// where it comes out. Returns the offset of the copy.
+// The copy slides in to the target from the tile
+// behind it, so with the twisted edges it appears
+// on the mirrored side, too
+func (b *block) ghostOffset() (core.Point, bool) {
 
-			s.updateSolidTile(b.pos.X, b.pos.Y, 0)
-			b.deactivated = true
-		}
+	if !b.teleporting && !b.jumping {
 
-		return blockNoHole
+		return core.NewPoint(0, 0), false
 	}
 
-	ret := b.handleMovement(s, ev)
-	b.computeRenderingPosition()
-
-	return ret
+	return core.NewPoint(
+		(b.target.X-b.pos.X-b.dir.X)*16,
+		(b.target.Y-b.pos.Y-b.dir.Y)*16), true
 }
 
-func (b *block) drawOutlines(c *core.Canvas, ap *core.AssetPack) {
+func (b *block) drawOutlines(c *core.Canvas, ap *core.AssetPack, col core.Color) {
 
 	if !b.exist {
 		return
 	}
 
-	c.FillRect(b.renderPos.X-1, b.renderPos.Y-1, 18, 18,
-		core.NewRGB(0, 0, 0))
-
-	if b.jumping {
-
-		if b.dir.X != 0 {
-
-			c.FillRect(b.renderPos.X-1-b.dir.X*c.Viewport().W,
-				b.renderPos.Y-1, 18, 18,
-				core.NewRGB(0, 0, 0))
+	c.FillRect(b.renderPos.X-1, b.renderPos.Y-1, 18, 18, col)
 
-		} else if b.dir.Y != 0 {
+	if off, ok := b.ghostOffset(); ok {
 
-			c.FillRect(b.renderPos.X-1,
-				b.renderPos.Y-1-b.dir.Y*c.Viewport().H,
-				18, 18, core.NewRGB(0, 0, 0))
-		}
+		c.FillRect(b.renderPos.X-1+off.X,
+			b.renderPos.Y-1+off.Y, 18, 18, col)
 	}
 }
 
@@ -249,21 +142,11 @@ func (b *block) drawShadow(c *core.Canvas, ap *core.AssetPack) {
 	c.DrawBitmap(bmp,
 		b.renderPos.X-1, b.renderPos.Y-1, core.FlipNone)
 
-	if b.jumping {
-
-		if b.dir.X != 0 {
-
-			c.DrawSprite(b.spr, bmp,
-				b.renderPos.X-1-b.dir.X*c.Viewport().W,
-				b.renderPos.Y-1, core.FlipNone)
+	if off, ok := b.ghostOffset(); ok {
 
-		} else if b.dir.Y != 0 {
-
-			c.DrawSprite(b.spr, bmp,
-				b.renderPos.X-1,
-				b.renderPos.Y-1-b.dir.Y*c.Viewport().H,
-				core.FlipNone)
-		}
+		c.DrawSprite(b.spr, bmp,
+			b.renderPos.X-1+off.X,
+			b.renderPos.Y-1+off.Y, core.FlipNone)
 	}
 }
 
@@ -278,25 +161,53 @@ func (b *block) draw(c *core.Canvas, ap *core.AssetPack) {
 	c.DrawSprite(b.spr, bmp,
 		b.renderPos.X, b.renderPos.Y, core.FlipNone)
 
-	if b.jumping {
+	if off, ok := b.ghostOffset(); ok {
+
+		c.DrawSprite(b.spr, bmp,
+			b.renderPos.X+off.X,
+			b.renderPos.Y+off.Y, core.FlipNone)
+	}
+}
+
+// Covers the edges between two cells of a multi-cell
+// block with the color of the face, if the other cell
+// is right or below this one
+func (b *block) drawJoint(c *core.Canvas, ap *core.AssetPack, other *block) {
+
+	if !b.exist || !other.exist {
+		return
+	}
+
+	bmp := ap.GetAsset("blocks").(*core.Bitmap)
+
+	sx := b.spr.Frame() * b.spr.Width()
+	sy := b.spr.Row() * b.spr.Height()
 
-		if b.dir.X != 0 {
+	// The face starts 3 pixels from the edges of the
+	// sprite, so there are 6 pixels of edges to cover
+	dx := other.renderPos.X - b.renderPos.X
+	dy := other.renderPos.Y - b.renderPos.Y
 
-			c.DrawSprite(b.spr, bmp,
-				b.renderPos.X-b.dir.X*c.Viewport().W,
-				b.renderPos.Y, core.FlipNone)
+	if dx == 16 && dy == 0 {
 
-		} else if b.dir.Y != 0 {
+		for x := int32(13); x < 19; x++ {
 
-			c.DrawSprite(b.spr, bmp,
-				b.renderPos.X,
-				b.renderPos.Y-b.dir.Y*c.Viewport().H,
-				core.FlipNone)
+			c.DrawBitmapRegion(bmp, sx+3, sy+3, 1, 10,
+				b.renderPos.X+x, b.renderPos.Y+3, core.FlipNone)
+		}
+
+	} else if dx == 0 && dy == 16 {
+
+		for y := int32(13); y < 19; y++ {
+
+			c.DrawBitmapRegion(bmp, sx+3, sy+3, 10, 1,
+				b.renderPos.X+3, b.renderPos.Y+y, core.FlipNone)
 		}
 	}
 }
 
-func newBlock(x, y, id int32) *block {
+// The sprite row is the variant of the block
+func newBlock(x, y, id, row int32) *block {
 
 	b := new(block)
 
@@ -310,14 +221,10 @@ func newBlock(x, y, id int32) *block {
 	b.exist = true
 
 	b.spr = core.NewSprite(16, 16)
-	b.spr.SetFrame(id, 0)
+	b.spr.SetFrame(id, row)
 
 	b.moveTimer = 0
 	b.moving = false
-	b.deactivated = true
-
-	b.playDestroy = false
-	b.playHit = false
 
 	return b
 }
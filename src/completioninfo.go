@@ -21,12 +21,18 @@ type completionInfo struct {
 	enterPressed bool // For this reason, RENAME THIS STRUCT
 }
 
-func (cinfo *completionInfo) updateState(index int32, state int32) {
+// Clearing a stage with hints is not worth a gold star
+func (cinfo *completionInfo) updateState(index int32, state int32, usedHints bool) {
 
 	if index < 1 || index > cinfo.levelCount() {
 		return
 	}
 
+	if usedHints {
+
+		state = core.MinInt32(state, 1)
+	}
+
 	cinfo.states[index-1] = core.MaxInt32(state, cinfo.states[index-1])
 }
 
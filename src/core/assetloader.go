@@ -4,8 +4,6 @@ import (
 	"encoding/xml"
 	"io/ioutil"
 	"os"
-
-	"github.com/veandco/go-sdl2/sdl"
 )
 
 // Types needed to parse XML data
@@ -34,11 +32,13 @@ type musicXML struct {
 	Name    string   `xml:"name,attr"`
 }
 
-func parseAssetFile(path string, renderer *sdl.Renderer) (*AssetPack, error) {
+// ParseAssetFile : Loads all the assets listed in
+// an asset file
+func ParseAssetFile(path string, backend RenderBackend) (*AssetPack, error) {
 
 	var err error
 
-	ap := newAssetPack(renderer)
+	ap := NewAssetPack(backend)
 
 	file, err := os.Open(path)
 	if err != nil {
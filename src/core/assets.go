@@ -1,9 +1,5 @@
 package core
 
-import (
-	"github.com/veandco/go-sdl2/sdl"
-)
-
 type asset struct {
 	data interface{}
 	name string
@@ -11,8 +7,8 @@ type asset struct {
 
 // AssetPack : Contains assets
 type AssetPack struct {
-	assets   []asset
-	renderer *sdl.Renderer
+	assets  []asset
+	backend RenderBackend
 }
 
 func (ap *AssetPack) dispose() {
@@ -39,11 +35,13 @@ func (ap *AssetPack) dispose() {
 	}
 }
 
-func newAssetPack(renderer *sdl.Renderer) *AssetPack {
+// NewAssetPack : Constructor for an empty asset pack.
+// Bitmaps are loaded with the given backend
+func NewAssetPack(backend RenderBackend) *AssetPack {
 
 	ap := new(AssetPack)
 
-	ap.renderer = renderer
+	ap.backend = backend
 	ap.assets = make([]asset, 0)
 
 	return ap
@@ -56,7 +54,7 @@ func (ap *AssetPack) AddBitmap(name string, path string) error {
 	var bmp *Bitmap
 	var a asset
 
-	bmp, err = loadBitmap(ap.renderer, path)
+	bmp, err = ap.backend.LoadBitmap(path)
 	if err != nil {
 
 		return err
@@ -0,0 +1,47 @@
+package core
+
+import "image"
+
+// BlendMode : How the drawn pixels are mixed with
+// the pixels of the target
+type BlendMode int32
+
+// Blend modes
+const (
+	BlendDefault BlendMode = 0 // Alpha blending
+	BlendMod     BlendMode = 1 // Multiply
+	BlendNone    BlendMode = 2 // Replace
+)
+
+// What a backend keeps for a bitmap, a texture
+// or the pixels, for instance
+type bitmapData interface {
+	dispose()
+}
+
+// RenderBackend : The low-level drawing operations the canvas
+// is built on. Coordinates are relative to the viewport, and
+// there is no translation, that is the job of the canvas
+type RenderBackend interface {
+	NewBitmap(width, height uint32, isTarget bool) (*Bitmap, error)
+	LoadBitmap(path string) (*Bitmap, error)
+	DisposeBitmap(bmp *Bitmap)
+
+	// A nil bitmap means the screen. Changing the target
+	// resets the viewport
+	SetTarget(bmp *Bitmap)
+	Target() *Bitmap
+	// A nil rectangle means the whole target
+	SetViewport(rect *Rectangle)
+
+	// Replaces the whole target, ignores the viewport
+	// and blending
+	Clear(color Color)
+	FillRect(rect Rectangle, color Color)
+	DrawBitmapRegion(bmp *Bitmap, src, dest Rectangle, flip Flip)
+
+	SetBitmapColor(bmp *Bitmap, r, g, b uint8)
+	SetBitmapAlpha(bmp *Bitmap, a uint8)
+
+	ReadPixels(bmp *Bitmap) (*image.RGBA, error)
+}
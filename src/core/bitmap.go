@@ -2,64 +2,29 @@ package core
 
 import (
 	"image"
+	"image/draw"
 	_ "image/png" // Required to load png files
 	"os"
-
-	"github.com/veandco/go-sdl2/sdl"
 )
 
-// Bitmap : A simple container for a texture
-// and its size
+// Bitmap : A simple container for whatever the
+// backend uses to store the image, and its size
 type Bitmap struct {
-	texture *sdl.Texture
-	width   uint32
-	height  uint32
+	data   bitmapData
+	mod    Color
+	width  uint32
+	height uint32
 }
 
-func newBitmap(width, height uint32, isTarget bool, rend *sdl.Renderer) (*Bitmap, error) {
-
-	var err error
-	var access int
-
-	bmp := new(Bitmap)
-
-	if isTarget {
-		access = sdl.TEXTUREACCESS_TARGET
-
-	} else {
-		access = sdl.TEXTUREACCESS_STATIC
-	}
-
-	bmp.width = width
-	bmp.height = height
-
-	bmp.texture, err = rend.CreateTexture(
-		sdl.PIXELFORMAT_RGBA8888,
-		access, int32(width), int32(height))
-
-	bmp.texture.SetBlendMode(sdl.BLENDMODE_BLEND)
-
-	return bmp, err
-}
-
-// The png decoder has some funny way to handle RGBA values,
-// so this function is required to correct them
-func correctRGBA(r, g, b, a uint32) (uint8, uint8, uint8, uint8) {
-
-	return uint8(r / 257), uint8(g / 257), uint8(b / 257), uint8(a / 257)
-}
-
-func loadBitmap(rend *sdl.Renderer, path string) (*Bitmap, error) {
-
-	var err error
-
-	bmp := new(Bitmap)
+// Decodes an image file to premultiplied RGBA
+func loadImage(path string) (*image.RGBA, error) {
 
 	file, err := os.Open(path)
 	if err != nil {
 
 		return nil, err
 	}
+	defer file.Close()
 
 	data, _, err := image.Decode(file)
 	if err != nil {
@@ -67,40 +32,11 @@ func loadBitmap(rend *sdl.Renderer, path string) (*Bitmap, error) {
 		return nil, err
 	}
 
-	bmp.width = uint32(data.Bounds().Max.X)
-	bmp.height = uint32(data.Bounds().Max.Y)
+	bounds := data.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(img, img.Bounds(), data, bounds.Min, draw.Src)
 
-	rmask := uint32(0x000000ff)
-	gmask := uint32(0x0000ff00)
-	bmask := uint32(0x00ff0000)
-	amask := uint32(0xff000000)
-
-	surf, err := sdl.CreateRGBSurface(0,
-		int32(bmp.width), int32(bmp.height), 32,
-		rmask, gmask, bmask, amask)
-	if err != nil {
-
-		return nil, err
-	}
-	pdata := surf.Pixels()
-
-	i := 0
-	for y := 0; y < int(bmp.height); y++ {
-
-		for x := 0; x < int(bmp.width); x++ {
-
-			pdata[i], pdata[i+1], pdata[i+2], pdata[i+3] =
-				correctRGBA(data.At(x, y).RGBA())
-			i += 4
-		}
-	}
-
-	bmp.texture, err = rend.CreateTextureFromSurface(surf)
-	if err != nil {
-		return nil, err
-	}
-
-	return bmp, err
+	return img, nil
 }
 
 // Width : What do you think? A getter for width!
@@ -118,5 +54,9 @@ func (bmp *Bitmap) Height() uint32 {
 // Dispose : Destroy the bitmap
 func (bmp *Bitmap) Dispose() {
 
-	bmp.texture.Destroy()
+	if bmp.data != nil {
+
+		bmp.data.dispose()
+	}
+	bmp.data = nil
 }
@@ -1,26 +1,22 @@
 package core
 
-import (
-	"github.com/veandco/go-sdl2/sdl"
-)
-
 // BitmapBuilder : Used so that the application
 // may create textures without having to have
-// access to the sdl.Renderer
+// access to the render backend
 type BitmapBuilder struct {
-	renderer *sdl.Renderer
+	backend RenderBackend
 }
 
-func newBitmapBuilder(renderer *sdl.Renderer) *BitmapBuilder {
+func newBitmapBuilder(backend RenderBackend) *BitmapBuilder {
 
 	bbuilder := new(BitmapBuilder)
 
-	bbuilder.renderer = renderer
+	bbuilder.backend = backend
 
 	return bbuilder
 }
 
 func (bbuilder *BitmapBuilder) build(width, height uint32, isTarget bool) (*Bitmap, error) {
 
-	return newBitmap(width, height, isTarget, bbuilder.renderer)
+	return bbuilder.backend.NewBitmap(width, height, isTarget)
 }
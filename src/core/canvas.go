@@ -1,9 +1,8 @@
 package core
 
 import (
+	"image"
 	"math"
-
-	"github.com/veandco/go-sdl2/sdl"
 )
 
 // RenderCallback : Used when rendering to user-created
@@ -21,41 +20,32 @@ const (
 	FlipBoth       = 3
 )
 
-// Blend modes
-const (
-	BlendDefault = sdl.BLENDMODE_BLEND
-	BlendMod     = sdl.BLENDMODE_MOD
-	BlendNone    = sdl.BLENDMODE_NONE
-)
-
 // Canvas : A "buffer" where the drawn content
-// is stored. In this case, a texture
+// is stored. In this case, a bitmap
 type Canvas struct {
 	width       uint32
 	height      uint32
 	translation Point
-	renderer    *sdl.Renderer
+	backend     RenderBackend
 	frame       *Bitmap
 	frameCopy   *Bitmap
-	frameTarget sdl.Rect
-	srcRect     sdl.Rect
-	destRect    sdl.Rect
+	frameTarget Rectangle
 	viewport    Rectangle
 }
 
-func (c *Canvas) initialize(renderer *sdl.Renderer) error {
+func (c *Canvas) initialize(backend RenderBackend) error {
 
 	var err error
 
-	c.renderer = renderer
+	c.backend = backend
 
-	c.frame, err = newBitmap(c.width, c.height, true, renderer)
+	c.frame, err = backend.NewBitmap(c.width, c.height, true)
 	if err != nil {
 
 		return err
 	}
 
-	c.frameCopy, err = newBitmap(c.width, c.height, true, renderer)
+	c.frameCopy, err = backend.NewBitmap(c.width, c.height, true)
 	if err != nil {
 
 		c.frame.Dispose()
@@ -69,19 +59,20 @@ func (c *Canvas) initialize(renderer *sdl.Renderer) error {
 
 func (c *Canvas) begin() {
 
-	_ = c.renderer.SetRenderTarget(c.frame.texture)
+	c.backend.SetTarget(c.frame)
 }
 
 func (c *Canvas) end() {
 
-	_ = c.renderer.SetRenderTarget(nil)
+	c.backend.SetTarget(nil)
 }
 
 func (c *Canvas) redrawFrame() {
 
 	c.Clear(0, 0, 0)
-	c.renderer.CopyEx(c.frame.texture,
-		nil, &c.frameTarget, 0.0, nil, sdl.FLIP_NONE)
+	c.backend.DrawBitmapRegion(c.frame,
+		NewRect(0, 0, int32(c.width), int32(c.height)),
+		c.frameTarget, FlipNone)
 }
 
 func (c *Canvas) resize(w, h int32) {
@@ -108,20 +99,14 @@ func (c *Canvas) dispose() {
 // Clear : Clear the screen with a color
 func (c *Canvas) Clear(r, g, b uint8) {
 
-	c.renderer.SetDrawColor(r, g, b, 255)
-	c.renderer.Clear()
+	c.backend.Clear(NewRGB(r, g, b))
 }
 
 // ClearToAlpha : Make the current render target
 // transparent
 func (c *Canvas) ClearToAlpha() {
 
-	c.renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
-
-	c.renderer.SetDrawColor(0, 0, 0, 0)
-	c.renderer.Clear()
-
-	c.renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	c.backend.Clear(NewRGBA(0, 0, 0, 0))
 }
 
 // DrawBitmap : Draw a full bitmap
@@ -137,11 +122,8 @@ func (c *Canvas) DrawBitmapRegion(bmp *Bitmap, sx, sy, sw, sh, dx, dy int32, fli
 	dx += c.translation.X
 	dy += c.translation.Y
 
-	c.srcRect = sdl.Rect{X: sx, Y: sy, W: sw, H: sh}
-	c.destRect = sdl.Rect{X: dx, Y: dy, W: sw, H: sh}
-
-	c.renderer.CopyEx(bmp.texture, &c.srcRect, &c.destRect,
-		0.0, nil, sdl.RendererFlip(flip))
+	c.backend.DrawBitmapRegion(bmp,
+		NewRect(sx, sy, sw, sh), NewRect(dx, dy, sw, sh), flip)
 }
 
 // DrawText : Draw text with a bitmap font. Note that
@@ -193,14 +175,10 @@ func (c *Canvas) DrawText(font *Bitmap, text string,
 // FillRect : Fills an rectangle
 func (c *Canvas) FillRect(x, y, w, h int32, color Color) {
 
-	c.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
-
 	x += c.translation.X
 	y += c.translation.Y
-	c.destRect = sdl.Rect{X: x, Y: y, W: w, H: h}
-
-	c.renderer.FillRect(&c.destRect)
 
+	c.backend.FillRect(NewRect(x, y, w, h), color)
 }
 
 // FillCircleOutside : Fill area outside the circle
@@ -290,11 +268,11 @@ func (c *Canvas) Move(dx, dy int32) {
 // DrawToBitmap : Use a bitmap as a render target
 func (c *Canvas) DrawToBitmap(bmp *Bitmap, ap *AssetPack, cb RenderCallback) {
 
-	oldTarget := c.renderer.GetRenderTarget()
+	oldTarget := c.backend.Target()
 
-	c.renderer.SetRenderTarget(bmp.texture)
+	c.backend.SetTarget(bmp)
 	cb(c, ap)
-	c.renderer.SetRenderTarget(oldTarget)
+	c.backend.SetTarget(oldTarget)
 }
 
 // CopyCurrentFrame : Copy current frame to the buffer,
@@ -325,23 +303,22 @@ func (c *Canvas) DrawCopiedFrameRegion(sx, sy, sw, sh,
 // a bitmap
 func (c *Canvas) SetBitmapColor(bmp *Bitmap, r, g, b uint8) {
 
-	bmp.texture.SetColorMod(r, g, b)
+	c.backend.SetBitmapColor(bmp, r, g, b)
 }
 
 // SetBitmapAlpha : Set alpha value to be used when drawing
 // a bitmap
 func (c *Canvas) SetBitmapAlpha(bmp *Bitmap, a uint8) {
 
-	bmp.texture.SetAlphaMod(a)
+	c.backend.SetBitmapAlpha(bmp, a)
 }
 
 // SetViewport : Set the current view area
 func (c *Canvas) SetViewport(x, y, w, h int32) {
 
-	c.destRect = sdl.Rect{X: x, Y: y, W: w, H: h}
 	c.viewport = NewRect(x, y, w, h)
 
-	c.renderer.SetViewport(&c.destRect)
+	c.backend.SetViewport(&c.viewport)
 }
 
 // ResetViewport : Reset the viewport to the whole
@@ -349,7 +326,7 @@ func (c *Canvas) SetViewport(x, y, w, h int32) {
 func (c *Canvas) ResetViewport() {
 
 	c.viewport = NewRect(0, 0, int32(c.width), int32(c.height))
-	c.renderer.SetViewport(nil)
+	c.backend.SetViewport(nil)
 }
 
 // Viewport : Getter for viewport
@@ -358,6 +335,19 @@ func (c *Canvas) Viewport() Rectangle {
 	return c.viewport
 }
 
+// Backend : Getter for the backend
+func (c *Canvas) Backend() RenderBackend {
+
+	return c.backend
+}
+
+// CaptureFrame : Returns a copy of the pixels of
+// the last drawn frame
+func (c *Canvas) CaptureFrame() (*image.RGBA, error) {
+
+	return c.backend.ReadPixels(c.frame)
+}
+
 // Width : A getter for width (it feels silly to comment
 // these things, seriously)
 func (c *Canvas) Width() uint32 {
@@ -400,12 +390,27 @@ func (cbuilder *CanvasBuilder) Build() *Canvas {
 
 	c.translation = NewPoint(0, 0)
 
-	c.srcRect = sdl.Rect{X: 0, Y: 0, W: 0, H: 0}
-	c.destRect = sdl.Rect{X: 0, Y: 0, W: 0, H: 0}
-
 	return c
 }
 
+// BuildHeadless : Builds a canvas that draws with a software
+// backend, so no window is needed. Everything is drawn
+// straight to the frame, which can be read with CaptureFrame
+func (cbuilder *CanvasBuilder) BuildHeadless() (*Canvas, error) {
+
+	c := cbuilder.Build()
+
+	err := c.initialize(NewSoftwareBackend(c.width, c.height))
+	if err != nil {
+
+		return nil, err
+	}
+	c.resize(int32(c.width), int32(c.height))
+	c.begin()
+
+	return c, nil
+}
+
 // SetDimensions : Set desired dimensions for the canvas to be built
 func (cbuilder *CanvasBuilder) SetDimensions(width, height uint32) *CanvasBuilder {
 
@@ -16,6 +16,7 @@ type GameWindow struct {
 	oldTime     uint32
 	window      *sdl.Window
 	renderer    *sdl.Renderer
+	backend     *sdlBackend
 	winID       uint32
 	input       *InputManager
 	baseCanvas  *Canvas
@@ -24,6 +25,7 @@ type GameWindow struct {
 	bbuilder    *BitmapBuilder
 	tr          *TransitionManager
 	audio       *AudioPlayer
+	capture     *frameCapture
 	ev          *Event
 	activeScene Scene
 	err         error
@@ -226,6 +228,8 @@ func (win *GameWindow) mainLoop() {
 		win.tr.Draw(win.baseCanvas)
 
 		win.baseCanvas.end()
+
+		win.capture.capture(win.baseCanvas)
 	}
 
 	win.baseCanvas.redrawFrame()
@@ -265,6 +269,20 @@ func (win *GameWindow) checkDefaultKeyShortcuts() {
 
 		win.running = false
 	}
+
+	// F12 saves a screenshot, Shift+F12 starts and stops
+	// a burst that saves every redrawn frame
+	if win.input.GetKeyState(KeyF12) == StatePressed {
+
+		if win.input.GetKeyState(KeyLshift)&StateDownOrPressed == 1 {
+
+			win.capture.toggleBurst()
+
+		} else {
+
+			win.capture.requestScreenshot()
+		}
+	}
 }
 
 func (win *GameWindow) changeScene(newScene Scene) {
@@ -366,8 +384,10 @@ func (builder *WindowBuilder) Build() (*GameWindow, error) {
 		return nil, err
 	}
 
+	window.backend = newSDLBackend(window.renderer)
+
 	window.baseCanvas = builder.baseCanvas
-	err = window.baseCanvas.initialize(window.renderer)
+	err = window.baseCanvas.initialize(window.backend)
 	if err != nil {
 
 		_ = window.window.Destroy()
@@ -380,7 +400,7 @@ func (builder *WindowBuilder) Build() (*GameWindow, error) {
 	// in that path
 	if builder.assetPath != "" {
 
-		window.assets, err = parseAssetFile(builder.assetPath, window.renderer)
+		window.assets, err = ParseAssetFile(builder.assetPath, window.backend)
 		if err != nil {
 
 			_ = window.window.Destroy()
@@ -391,12 +411,12 @@ func (builder *WindowBuilder) Build() (*GameWindow, error) {
 
 	} else {
 
-		window.assets = newAssetPack(window.renderer)
+		window.assets = NewAssetPack(window.backend)
 	}
 
 	if builder.input == nil {
 
-		window.input = newInputManager()
+		window.input = NewInputManager()
 	} else {
 
 		window.input = builder.input
@@ -404,8 +424,9 @@ func (builder *WindowBuilder) Build() (*GameWindow, error) {
 
 	window.tr = NewTransitionManager()
 	window.audio = NewAudioPlayer(builder.sfxVolume, builder.musicVolume)
+	window.capture = newFrameCapture()
 
-	window.bbuilder = newBitmapBuilder(window.renderer)
+	window.bbuilder = newBitmapBuilder(window.backend)
 	window.ev = newEvent(window, 0, window.input, window.assets,
 		window.bbuilder, window.tr, window.audio)
 
@@ -107,6 +107,9 @@ type InputManager struct {
 	deltaAxes []float32
 
 	actions []action
+
+	queue []queuedEvent
+	tick  uint64
 }
 
 func (input *InputManager) keyPressed(index uint32) {
@@ -209,9 +212,20 @@ func (input *InputManager) refresh() {
 		input.oldAxes[i] = axis
 	}
 
+	input.tick++
+	input.flushVirtualEvents()
+}
+
+// Refresh : Advance the input states by one tick. The game
+// window does this, call only when running without one
+func (input *InputManager) Refresh() {
+
+	input.refresh()
 }
 
-func newInputManager() *InputManager {
+// NewInputManager : Constructor for an input manager
+// with no actions
+func NewInputManager() *InputManager {
 
 	const maxButtons = 16
 	const maxAxes = 8
@@ -227,6 +241,9 @@ func newInputManager() *InputManager {
 
 	input.actions = make([]action, 0)
 
+	input.queue = make([]queuedEvent, 0)
+	input.tick = 0
+
 	return input
 }
 
@@ -259,3 +276,42 @@ func (input *InputManager) GetKeyState(scancode uint32) State {
 
 	return input.keyStates.getState(scancode)
 }
+
+// ActionNames : Returns the names of the actions, in the
+// same order GetActionStates returns their states
+func (input *InputManager) ActionNames() []string {
+
+	names := make([]string, len(input.actions))
+	for i, a := range input.actions {
+
+		names[i] = a.name
+	}
+	return names
+}
+
+// GetActionStates : Returns the current states of all
+// the actions
+func (input *InputManager) GetActionStates() []State {
+
+	states := make([]State, len(input.actions))
+	for i, a := range input.actions {
+
+		states[i] = a.state
+	}
+	return states
+}
+
+// SetActionStates : Override the states of the actions, for
+// example to play back recorded input. The next refresh
+// computes the states from the devices again
+func (input *InputManager) SetActionStates(states []State) {
+
+	for i := range input.actions {
+
+		if i >= len(states) {
+
+			break
+		}
+		input.actions[i].state = states[i]
+	}
+}
@@ -41,7 +41,7 @@ func ParseKeyConfiguration(path string) (*InputManager, error) {
 		return nil, err
 	}
 	
-	input := newInputManager()
+	input := NewInputManager()
 	
 	var kconfXML keyConfigXML
 	xml.Unmarshal(byteValue, &kconfXML)
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"time"
+)
+
+const (
+	screenshotFolder = "screenshots"
+)
+
+// Takes single screenshots and bursts of them
+type frameCapture struct {
+	requested  bool
+	burst      bool
+	burstPath  string
+	burstFrame int32
+}
+
+func timestamp() string {
+
+	return time.Now().Format("20060102_150405")
+}
+
+func savePNG(img *image.RGBA, path string) error {
+
+	file, err := os.Create(path)
+	if err != nil {
+
+		return err
+	}
+	defer file.Close()
+
+	return png.Encode(file, img)
+}
+
+func (fc *frameCapture) requestScreenshot() {
+
+	fc.requested = true
+}
+
+func (fc *frameCapture) toggleBurst() {
+
+	if fc.burst {
+
+		fmt.Printf("Burst capture stopped after %d frames\n", fc.burstFrame)
+		fc.burst = false
+		return
+	}
+
+	fc.burstPath = screenshotFolder + "/burst_" + timestamp()
+	err := os.MkdirAll(fc.burstPath, 0755)
+	if err != nil {
+
+		fmt.Printf("Error starting a burst capture: %s\n", err.Error())
+		return
+	}
+
+	fc.burst = true
+	fc.burstFrame = 0
+
+	fmt.Printf("Burst capture started, writing to %s\n", fc.burstPath)
+}
+
+// Called after a frame has been redrawn
+func (fc *frameCapture) capture(c *Canvas) {
+
+	if !fc.requested && !fc.burst {
+
+		return
+	}
+
+	img, err := c.CaptureFrame()
+	if err != nil {
+
+		fmt.Printf("Error reading the frame: %s\n", err.Error())
+		fc.requested = false
+		fc.burst = false
+		return
+	}
+
+	if fc.requested {
+
+		fc.requested = false
+
+		path := screenshotFolder + "/screenshot_" + timestamp() + ".png"
+		err = os.MkdirAll(screenshotFolder, 0755)
+		if err == nil {
+
+			err = savePNG(img, path)
+		}
+
+		if err != nil {
+
+			fmt.Printf("Error saving a screenshot: %s\n", err.Error())
+		} else {
+
+			fmt.Printf("Screenshot saved to %s\n", path)
+		}
+	}
+
+	if fc.burst {
+
+		err = savePNG(img, fmt.Sprintf("%s/frame_%05d.png",
+			fc.burstPath, fc.burstFrame))
+		if err != nil {
+
+			fmt.Printf("Error saving a burst frame: %s\n", err.Error())
+			fc.burst = false
+			return
+		}
+		fc.burstFrame++
+	}
+}
+
+func newFrameCapture() *frameCapture {
+
+	fc := new(frameCapture)
+
+	fc.requested = false
+	fc.burst = false
+
+	return fc
+}
@@ -0,0 +1,270 @@
+package core
+
+import (
+	"errors"
+	"image"
+	"os"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// The texture of a bitmap drawn with SDL
+type sdlTexture struct {
+	texture *sdl.Texture
+}
+
+func (t *sdlTexture) dispose() {
+
+	t.texture.Destroy()
+}
+
+// Nil if the bitmap was not created by the SDL backend
+func textureOf(bmp *Bitmap) *sdl.Texture {
+
+	if t, ok := bmp.data.(*sdlTexture); ok {
+
+		return t.texture
+	}
+	return nil
+}
+
+func sdlBlendMode(mode BlendMode) sdl.BlendMode {
+
+	switch mode {
+
+	case BlendMod:
+		return sdl.BLENDMODE_MOD
+
+	case BlendNone:
+		return sdl.BLENDMODE_NONE
+
+	default:
+		break
+	}
+	return sdl.BLENDMODE_BLEND
+}
+
+func newBitmap(width, height uint32, isTarget bool, rend *sdl.Renderer) (*Bitmap, error) {
+
+	var err error
+	var access int
+
+	bmp := new(Bitmap)
+
+	if isTarget {
+		access = sdl.TEXTUREACCESS_TARGET
+
+	} else {
+		access = sdl.TEXTUREACCESS_STATIC
+	}
+
+	bmp.width = width
+	bmp.height = height
+	bmp.mod = NewRGB(255, 255, 255)
+
+	texture, err := rend.CreateTexture(
+		sdl.PIXELFORMAT_RGBA8888,
+		access, int32(width), int32(height))
+	if err != nil {
+
+		return nil, err
+	}
+	texture.SetBlendMode(sdlBlendMode(BlendDefault))
+	bmp.data = &sdlTexture{texture: texture}
+
+	return bmp, err
+}
+
+// The png decoder has some funny way to handle RGBA values,
+// so this function is required to correct them
+func correctRGBA(r, g, b, a uint32) (uint8, uint8, uint8, uint8) {
+
+	return uint8(r / 257), uint8(g / 257), uint8(b / 257), uint8(a / 257)
+}
+
+func loadBitmap(rend *sdl.Renderer, path string) (*Bitmap, error) {
+
+	var err error
+
+	bmp := new(Bitmap)
+	bmp.mod = NewRGB(255, 255, 255)
+
+	file, err := os.Open(path)
+	if err != nil {
+
+		return nil, err
+	}
+	defer file.Close()
+
+	data, _, err := image.Decode(file)
+	if err != nil {
+
+		return nil, err
+	}
+
+	bmp.width = uint32(data.Bounds().Max.X)
+	bmp.height = uint32(data.Bounds().Max.Y)
+
+	rmask := uint32(0x000000ff)
+	gmask := uint32(0x0000ff00)
+	bmask := uint32(0x00ff0000)
+	amask := uint32(0xff000000)
+
+	surf, err := sdl.CreateRGBSurface(0,
+		int32(bmp.width), int32(bmp.height), 32,
+		rmask, gmask, bmask, amask)
+	if err != nil {
+
+		return nil, err
+	}
+	pdata := surf.Pixels()
+
+	i := 0
+	for y := 0; y < int(bmp.height); y++ {
+
+		for x := 0; x < int(bmp.width); x++ {
+
+			pdata[i], pdata[i+1], pdata[i+2], pdata[i+3] =
+				correctRGBA(data.At(x, y).RGBA())
+			i += 4
+		}
+	}
+
+	texture, err := rend.CreateTextureFromSurface(surf)
+	if err != nil {
+		return nil, err
+	}
+	bmp.data = &sdlTexture{texture: texture}
+
+	return bmp, err
+}
+
+// Draws with the SDL renderer, that is, the GPU
+type sdlBackend struct {
+	renderer *sdl.Renderer
+	target   *Bitmap
+}
+
+func newSDLBackend(renderer *sdl.Renderer) *sdlBackend {
+
+	sb := new(sdlBackend)
+
+	sb.renderer = renderer
+	sb.target = nil
+
+	return sb
+}
+
+func (sb *sdlBackend) NewBitmap(width, height uint32, isTarget bool) (*Bitmap, error) {
+
+	return newBitmap(width, height, isTarget, sb.renderer)
+}
+
+func (sb *sdlBackend) LoadBitmap(path string) (*Bitmap, error) {
+
+	return loadBitmap(sb.renderer, path)
+}
+
+func (sb *sdlBackend) DisposeBitmap(bmp *Bitmap) {
+
+	bmp.Dispose()
+}
+
+func (sb *sdlBackend) SetTarget(bmp *Bitmap) {
+
+	sb.target = bmp
+	if bmp == nil {
+
+		_ = sb.renderer.SetRenderTarget(nil)
+		return
+	}
+	_ = sb.renderer.SetRenderTarget(textureOf(bmp))
+}
+
+func (sb *sdlBackend) Target() *Bitmap {
+
+	return sb.target
+}
+
+func (sb *sdlBackend) SetViewport(rect *Rectangle) {
+
+	if rect == nil {
+
+		sb.renderer.SetViewport(nil)
+		return
+	}
+	sb.renderer.SetViewport(&sdl.Rect{X: rect.X, Y: rect.Y, W: rect.W, H: rect.H})
+}
+
+func (sb *sdlBackend) Clear(color Color) {
+
+	sb.renderer.SetDrawBlendMode(sdlBlendMode(BlendNone))
+
+	sb.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+	sb.renderer.Clear()
+
+	sb.renderer.SetDrawBlendMode(sdlBlendMode(BlendDefault))
+}
+
+func (sb *sdlBackend) FillRect(rect Rectangle, color Color) {
+
+	sb.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+	sb.renderer.FillRect(&sdl.Rect{X: rect.X, Y: rect.Y, W: rect.W, H: rect.H})
+}
+
+func (sb *sdlBackend) DrawBitmapRegion(bmp *Bitmap, src, dest Rectangle, flip Flip) {
+
+	sb.renderer.CopyEx(textureOf(bmp),
+		&sdl.Rect{X: src.X, Y: src.Y, W: src.W, H: src.H},
+		&sdl.Rect{X: dest.X, Y: dest.Y, W: dest.W, H: dest.H},
+		0.0, nil, sdl.RendererFlip(flip))
+}
+
+func (sb *sdlBackend) SetBitmapColor(bmp *Bitmap, r, g, b uint8) {
+
+	bmp.mod.R, bmp.mod.G, bmp.mod.B = r, g, b
+	textureOf(bmp).SetColorMod(r, g, b)
+}
+
+func (sb *sdlBackend) SetBitmapAlpha(bmp *Bitmap, a uint8) {
+
+	bmp.mod.A = a
+	textureOf(bmp).SetAlphaMod(a)
+}
+
+// Only works for render targets
+func (sb *sdlBackend) ReadPixels(bmp *Bitmap) (*image.RGBA, error) {
+
+	if bmp == nil {
+
+		return nil, errors.New("cannot read the pixels of the screen")
+	}
+
+	oldTarget := sb.target
+	sb.SetTarget(bmp)
+	defer sb.SetTarget(oldTarget)
+
+	img := image.NewRGBA(image.Rect(0, 0, int(bmp.width), int(bmp.height)))
+
+	// ABGR in the native byte order means RGBA
+	// in memory, like in image.RGBA
+	err := sb.renderer.ReadPixels(nil, sdl.PIXELFORMAT_ABGR8888,
+		unsafe.Pointer(&img.Pix[0]), img.Stride)
+	if err != nil {
+
+		return nil, err
+	}
+
+	// SDL does not premultiply, Go does
+	var a uint32
+	for i := 0; i < len(img.Pix); i += 4 {
+
+		a = uint32(img.Pix[i+3])
+		img.Pix[i] = uint8(uint32(img.Pix[i]) * a / 255)
+		img.Pix[i+1] = uint8(uint32(img.Pix[i+1]) * a / 255)
+		img.Pix[i+2] = uint8(uint32(img.Pix[i+2]) * a / 255)
+	}
+
+	return img, nil
+}
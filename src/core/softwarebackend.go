@@ -0,0 +1,295 @@
+package core
+
+import (
+	"errors"
+	"image"
+)
+
+// The pixels of a bitmap drawn by the software backend
+type softwareImage struct {
+	pixels *image.RGBA
+}
+
+func (si *softwareImage) dispose() {
+
+	si.pixels = nil
+}
+
+// Nil if the bitmap was not created by the software backend
+func pixelsOf(bmp *Bitmap) *image.RGBA {
+
+	if si, ok := bmp.data.(*softwareImage); ok {
+
+		return si.pixels
+	}
+	return nil
+}
+
+// SoftwareBackend : Rasterizes everything to images in
+// the main memory, so no window or GPU is needed. Slow,
+// but good enough for drawing single frames in tools
+// and for comparing them to reference images
+type SoftwareBackend struct {
+	screen   *image.RGBA
+	target   *Bitmap
+	viewport *Rectangle
+}
+
+// NewSoftwareBackend : Constructor for a software backend
+// with a "screen" of the given size
+func NewSoftwareBackend(width, height uint32) *SoftwareBackend {
+
+	sb := new(SoftwareBackend)
+
+	sb.screen = image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	sb.target = nil
+	sb.viewport = nil
+
+	return sb
+}
+
+func (sb *SoftwareBackend) targetImage() *image.RGBA {
+
+	if sb.target == nil {
+
+		return sb.screen
+	}
+	return pixelsOf(sb.target)
+}
+
+// Returns the drawing area in the target coordinates
+func (sb *SoftwareBackend) clipArea() image.Rectangle {
+
+	area := sb.targetImage().Bounds()
+	if sb.viewport != nil {
+
+		area = area.Intersect(image.Rect(
+			int(sb.viewport.X), int(sb.viewport.Y),
+			int(sb.viewport.X+sb.viewport.W), int(sb.viewport.Y+sb.viewport.H)))
+	}
+	return area
+}
+
+func (sb *SoftwareBackend) origin() (int32, int32) {
+
+	if sb.viewport == nil {
+
+		return 0, 0
+	}
+	return sb.viewport.X, sb.viewport.Y
+}
+
+// Blends a premultiplied color to a pixel
+func blendPixel(pix []uint8, r, g, b, a uint32) {
+
+	inv := 255 - a
+
+	pix[0] = uint8(r + uint32(pix[0])*inv/255)
+	pix[1] = uint8(g + uint32(pix[1])*inv/255)
+	pix[2] = uint8(b + uint32(pix[2])*inv/255)
+	pix[3] = uint8(a + uint32(pix[3])*inv/255)
+}
+
+// NewBitmap : See RenderBackend
+func (sb *SoftwareBackend) NewBitmap(width, height uint32, isTarget bool) (*Bitmap, error) {
+
+	bmp := new(Bitmap)
+
+	bmp.width = width
+	bmp.height = height
+	bmp.mod = NewRGB(255, 255, 255)
+	bmp.data = &softwareImage{
+		pixels: image.NewRGBA(image.Rect(0, 0, int(width), int(height)))}
+
+	return bmp, nil
+}
+
+// LoadBitmap : See RenderBackend
+func (sb *SoftwareBackend) LoadBitmap(path string) (*Bitmap, error) {
+
+	img, err := loadImage(path)
+	if err != nil {
+
+		return nil, err
+	}
+
+	bmp := new(Bitmap)
+
+	bmp.width = uint32(img.Bounds().Dx())
+	bmp.height = uint32(img.Bounds().Dy())
+	bmp.mod = NewRGB(255, 255, 255)
+	bmp.data = &softwareImage{pixels: img}
+
+	return bmp, nil
+}
+
+// DisposeBitmap : See RenderBackend
+func (sb *SoftwareBackend) DisposeBitmap(bmp *Bitmap) {
+
+	bmp.Dispose()
+}
+
+// SetTarget : See RenderBackend
+func (sb *SoftwareBackend) SetTarget(bmp *Bitmap) {
+
+	sb.target = bmp
+	sb.viewport = nil
+}
+
+// Target : See RenderBackend
+func (sb *SoftwareBackend) Target() *Bitmap {
+
+	return sb.target
+}
+
+// SetViewport : See RenderBackend
+func (sb *SoftwareBackend) SetViewport(rect *Rectangle) {
+
+	if rect == nil {
+
+		sb.viewport = nil
+		return
+	}
+	vp := *rect
+	sb.viewport = &vp
+}
+
+// Clear : See RenderBackend
+func (sb *SoftwareBackend) Clear(color Color) {
+
+	img := sb.targetImage()
+
+	a := uint32(color.A)
+	r := uint8(uint32(color.R) * a / 255)
+	g := uint8(uint32(color.G) * a / 255)
+	b := uint8(uint32(color.B) * a / 255)
+
+	for i := 0; i < len(img.Pix); i += 4 {
+
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] =
+			r, g, b, color.A
+	}
+}
+
+// FillRect : See RenderBackend
+func (sb *SoftwareBackend) FillRect(rect Rectangle, color Color) {
+
+	img := sb.targetImage()
+	ox, oy := sb.origin()
+
+	area := sb.clipArea().Intersect(image.Rect(
+		int(rect.X+ox), int(rect.Y+oy),
+		int(rect.X+ox+rect.W), int(rect.Y+oy+rect.H)))
+
+	a := uint32(color.A)
+	r := uint32(color.R) * a / 255
+	g := uint32(color.G) * a / 255
+	b := uint32(color.B) * a / 255
+
+	var i int
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+
+		for x := area.Min.X; x < area.Max.X; x++ {
+
+			i = img.PixOffset(x, y)
+			blendPixel(img.Pix[i:i+4], r, g, b, a)
+		}
+	}
+}
+
+// DrawBitmapRegion : See RenderBackend. Scaling uses
+// the nearest neighbour
+func (sb *SoftwareBackend) DrawBitmapRegion(bmp *Bitmap, src, dest Rectangle, flip Flip) {
+
+	pixels := pixelsOf(bmp)
+	if pixels == nil || src.W <= 0 || src.H <= 0 ||
+		dest.W <= 0 || dest.H <= 0 {
+
+		return
+	}
+
+	img := sb.targetImage()
+	ox, oy := sb.origin()
+
+	dest.X += ox
+	dest.Y += oy
+
+	area := sb.clipArea().Intersect(image.Rect(
+		int(dest.X), int(dest.Y),
+		int(dest.X+dest.W), int(dest.Y+dest.H)))
+
+	srcBounds := pixels.Bounds()
+	mod := bmp.mod
+
+	var u, v int32
+	var i, j int
+	var a uint32
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+
+		v = (int32(y) - dest.Y) * src.H / dest.H
+		if flip&FlipVertical != 0 {
+
+			v = src.H - 1 - v
+		}
+		v += src.Y
+
+		for x := area.Min.X; x < area.Max.X; x++ {
+
+			u = (int32(x) - dest.X) * src.W / dest.W
+			if flip&FlipHorizontal != 0 {
+
+				u = src.W - 1 - u
+			}
+			u += src.X
+
+			if !(image.Point{X: int(u), Y: int(v)}).In(srcBounds) {
+				continue
+			}
+
+			j = pixels.PixOffset(int(u), int(v))
+			a = uint32(pixels.Pix[j+3]) * uint32(mod.A) / 255
+			if a == 0 {
+				continue
+			}
+
+			i = img.PixOffset(x, y)
+			blendPixel(img.Pix[i:i+4],
+				uint32(pixels.Pix[j])*uint32(mod.R)/255*uint32(mod.A)/255,
+				uint32(pixels.Pix[j+1])*uint32(mod.G)/255*uint32(mod.A)/255,
+				uint32(pixels.Pix[j+2])*uint32(mod.B)/255*uint32(mod.A)/255,
+				a)
+		}
+	}
+}
+
+// SetBitmapColor : See RenderBackend
+func (sb *SoftwareBackend) SetBitmapColor(bmp *Bitmap, r, g, b uint8) {
+
+	bmp.mod.R, bmp.mod.G, bmp.mod.B = r, g, b
+}
+
+// SetBitmapAlpha : See RenderBackend
+func (sb *SoftwareBackend) SetBitmapAlpha(bmp *Bitmap, a uint8) {
+
+	bmp.mod.A = a
+}
+
+// ReadPixels : See RenderBackend. A nil bitmap
+// means the screen
+func (sb *SoftwareBackend) ReadPixels(bmp *Bitmap) (*image.RGBA, error) {
+
+	src := sb.screen
+	if bmp != nil {
+
+		src = pixelsOf(bmp)
+	}
+	if src == nil {
+
+		return nil, errors.New("the bitmap was not created by this backend")
+	}
+
+	img := image.NewRGBA(src.Bounds())
+	copy(img.Pix, src.Pix)
+
+	return img, nil
+}
@@ -0,0 +1,324 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+// The colors used in the expected images
+var testPalette = map[byte]Color{
+	'k': NewRGB(0, 0, 0),
+	'R': NewRGB(255, 0, 0),
+	'G': NewRGB(0, 255, 0),
+	'B': NewRGB(0, 0, 255),
+	'W': NewRGB(255, 255, 255),
+	'M': NewRGB(255, 0, 255),
+	// Half transparent over black
+	'r': NewRGB(128, 0, 0),
+	'g': NewRGB(0, 128, 0),
+	'b': NewRGB(0, 0, 128),
+	'w': NewRGB(128, 128, 128),
+}
+
+// A single drawing on a 4x4 canvas cleared to black. The
+// sprite is 2x2, red and green on the top row, blue and
+// white on the bottom row
+type drawCase struct {
+	name string
+	draw func(c *Canvas, sprite *Bitmap)
+	want []string
+}
+
+func formatImage(img *image.RGBA) string {
+
+	s := ""
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+
+		s += "\n"
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+
+			i := img.PixOffset(x, y)
+			s += fmt.Sprintf(" %02x%02x%02x%02x",
+				img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3])
+		}
+	}
+	return s
+}
+
+func expectedImage(t *testing.T, rows []string) *image.RGBA {
+
+	img := image.NewRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+
+		for x := 0; x < len(row); x++ {
+
+			col, ok := testPalette[row[x]]
+			if !ok {
+
+				t.Fatalf("no color for %q", row[x])
+			}
+			// Only opaque colors in the palette, so there
+			// is nothing to premultiply
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] =
+				col.R, col.G, col.B, col.A
+		}
+	}
+	return img
+}
+
+func compareImages(t *testing.T, got *image.RGBA, rows []string) {
+
+	want := expectedImage(t, rows)
+	if got.Bounds() != want.Bounds() {
+
+		t.Fatalf("got size %v, expected %v", got.Bounds(), want.Bounds())
+	}
+	for i := range want.Pix {
+
+		if got.Pix[i] != want.Pix[i] {
+
+			t.Fatalf("got image%s\nexpected%s", formatImage(got), formatImage(want))
+		}
+	}
+}
+
+func newTestCanvas(t *testing.T) (*Canvas, *Bitmap) {
+
+	c, err := NewCanvasBuilder().SetDimensions(4, 4).BuildHeadless()
+	if err != nil {
+
+		t.Fatal(err)
+	}
+	sprite, err := c.Backend().LoadBitmap("testdata/sprite.png")
+	if err != nil {
+
+		t.Fatal(err)
+	}
+	c.Clear(0, 0, 0)
+
+	return c, sprite
+}
+
+var drawCases = []drawCase{
+	{
+		name: "draws a bitmap",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.DrawBitmap(sprite, 1, 1, FlipNone)
+		},
+		want: []string{
+			"kkkk",
+			"kRGk",
+			"kBWk",
+			"kkkk",
+		},
+	},
+	{
+		name: "flips horizontally",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.DrawBitmap(sprite, 1, 1, FlipHorizontal)
+		},
+		want: []string{
+			"kkkk",
+			"kGRk",
+			"kWBk",
+			"kkkk",
+		},
+	},
+	{
+		name: "flips vertically",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.DrawBitmap(sprite, 1, 1, FlipVertical)
+		},
+		want: []string{
+			"kkkk",
+			"kBWk",
+			"kRGk",
+			"kkkk",
+		},
+	},
+	{
+		name: "flips both ways",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.DrawBitmap(sprite, 1, 1, FlipBoth)
+		},
+		want: []string{
+			"kkkk",
+			"kWBk",
+			"kGRk",
+			"kkkk",
+		},
+	},
+	{
+		name: "flips a region",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.DrawBitmapRegion(sprite, 0, 0, 2, 1, 0, 0, FlipHorizontal)
+		},
+		want: []string{
+			"GRkk",
+			"kkkk",
+			"kkkk",
+			"kkkk",
+		},
+	},
+	{
+		name: "modulates the color",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.SetBitmapColor(sprite, 255, 0, 255)
+			c.DrawBitmap(sprite, 1, 1, FlipNone)
+		},
+		want: []string{
+			"kkkk",
+			"kRkk",
+			"kBMk",
+			"kkkk",
+		},
+	},
+	{
+		name: "modulates the alpha",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.SetBitmapAlpha(sprite, 128)
+			c.DrawBitmap(sprite, 1, 1, FlipNone)
+		},
+		want: []string{
+			"kkkk",
+			"krgk",
+			"kbwk",
+			"kkkk",
+		},
+	},
+	{
+		name: "blends a transparent rectangle",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.FillRect(0, 0, 2, 1, NewRGBA(255, 255, 255, 128))
+		},
+		want: []string{
+			"wwkk",
+			"kkkk",
+			"kkkk",
+			"kkkk",
+		},
+	},
+	{
+		name: "clips to the edges of the target",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.DrawBitmap(sprite, -1, 3, FlipNone)
+			c.DrawBitmap(sprite, 3, -1, FlipNone)
+		},
+		want: []string{
+			"kkkB",
+			"kkkk",
+			"kkkk",
+			"Gkkk",
+		},
+	},
+	{
+		name: "draws relative to the viewport and clips to it",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.SetViewport(1, 1, 2, 2)
+			c.DrawBitmap(sprite, 1, 0, FlipNone)
+			c.FillRect(-1, 1, 2, 4, NewRGB(0, 0, 255))
+			c.ResetViewport()
+		},
+		want: []string{
+			"kkkk",
+			"kkRk",
+			"kBBk",
+			"kkkk",
+		},
+	},
+	{
+		name: "translates, then clips to the viewport",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			c.SetViewport(0, 0, 3, 3)
+			c.MoveTo(2, 2)
+			c.DrawBitmap(sprite, 0, 0, FlipNone)
+			c.MoveTo(0, 0)
+			c.ResetViewport()
+		},
+		want: []string{
+			"kkkk",
+			"kkkk",
+			"kkRk",
+			"kkkk",
+		},
+	},
+	{
+		name: "draws to a bitmap, then the bitmap to the frame",
+		draw: func(c *Canvas, sprite *Bitmap) {
+
+			bmp, _ := c.Backend().NewBitmap(3, 3, true)
+			c.DrawToBitmap(bmp, nil, func(c *Canvas, ap *AssetPack) {
+
+				c.ClearToAlpha()
+				c.DrawBitmap(sprite, 1, 1, FlipBoth)
+			})
+			// Back to the frame, with the transparent
+			// parts of the bitmap letting black through
+			c.DrawBitmap(bmp, 0, 0, FlipNone)
+			c.FillRect(3, 3, 1, 1, NewRGB(255, 0, 255))
+		},
+		want: []string{
+			"kkkk",
+			"kWBk",
+			"kGRk",
+			"kkkM",
+		},
+	},
+}
+
+func TestSoftwareBackend(t *testing.T) {
+
+	for _, tc := range drawCases {
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			c, sprite := newTestCanvas(t)
+			tc.draw(c, sprite)
+
+			img, err := c.CaptureFrame()
+			if err != nil {
+
+				t.Fatal(err)
+			}
+			compareImages(t, img, tc.want)
+		})
+	}
+}
+
+func TestReadBitmapPixels(t *testing.T) {
+
+	c, sprite := newTestCanvas(t)
+	bmp, err := c.Backend().NewBitmap(2, 2, true)
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	c.DrawToBitmap(bmp, nil, func(c *Canvas, ap *AssetPack) {
+
+		c.DrawBitmap(sprite, 0, 0, FlipVertical)
+	})
+
+	img, err := c.Backend().ReadPixels(bmp)
+	if err != nil {
+
+		t.Fatal(err)
+	}
+	compareImages(t, img, []string{
+		"BW",
+		"RG",
+	})
+}
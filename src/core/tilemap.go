@@ -12,22 +12,54 @@ import (
 
 type layer []int32
 
+// TilemapObject : An object in an object layer. The
+// position and the size are in pixels, like in Tiled
+type TilemapObject struct {
+	Name       string
+	Type       string
+	X, Y       float32
+	Width      float32
+	Height     float32
+	properties []keyValuePair
+}
+
 // Tilemap : contains data for a multilayer
 // tilemap
 type Tilemap struct {
 	layers     []layer
+	objects    []TilemapObject
 	properties []keyValuePair
 	width      int32
 	height     int32
+	tileWidth  int32
+	tileHeight int32
+	warnings   []string
 }
 
 // Required to parse XML
 type tmx struct {
-	XMLName    xml.Name      `xml:"map"`
-	Width      int32         `xml:"width,attr"`
-	Height     int32         `xml:"height,attr"`
+	XMLName      xml.Name         `xml:"map"`
+	Width        int32            `xml:"width,attr"`
+	Height       int32            `xml:"height,attr"`
+	TileWidth    int32            `xml:"tilewidth,attr"`
+	TileHeight   int32            `xml:"tileheight,attr"`
+	Properties   propertiesXML    `xml:"properties"`
+	Layers       []layerXML       `xml:"layer"`
+	ObjectGroups []objectGroupXML `xml:"objectgroup"`
+}
+type objectGroupXML struct {
+	XMLName xml.Name    `xml:"objectgroup"`
+	Objects []objectXML `xml:"object"`
+}
+type objectXML struct {
+	XMLName    xml.Name      `xml:"object"`
+	Name       string        `xml:"name,attr"`
+	Type       string        `xml:"type,attr"`
+	X          float32       `xml:"x,attr"`
+	Y          float32       `xml:"y,attr"`
+	Width      float32       `xml:"width,attr"`
+	Height     float32       `xml:"height,attr"`
 	Properties propertiesXML `xml:"properties"`
-	Layers     []layerXML    `xml:"layer"`
 }
 type propertiesXML struct {
 	XMLName    xml.Name      `xml:"properties"`
@@ -55,6 +87,62 @@ func (t *Tilemap) Height() int32 {
 	return t.height
 }
 
+// TileWidth : Width of a tile in pixels
+func (t *Tilemap) TileWidth() int32 {
+
+	return t.tileWidth
+}
+
+// TileHeight : Height of a tile in pixels
+func (t *Tilemap) TileHeight() int32 {
+
+	return t.tileHeight
+}
+
+// Objects : The objects of all the object layers
+func (t *Tilemap) Objects() []TilemapObject {
+
+	return t.objects
+}
+
+// GetProperty : Get value of an object property given a key. If
+// the property does not exist, return default
+func (o *TilemapObject) GetProperty(key string, def string) string {
+
+	for _, p := range o.properties {
+
+		if p.key == key {
+
+			return p.value
+		}
+	}
+	return def
+}
+
+// HasProperty : Tells if the object has a property with the given key
+func (o *TilemapObject) HasProperty(key string) bool {
+
+	for _, p := range o.properties {
+
+		if p.key == key {
+
+			return true
+		}
+	}
+	return false
+}
+
+// GetNumericProperty : Get value of a numeric object property
+func (o *TilemapObject) GetNumericProperty(key string, def int32) int32 {
+
+	v, err := strconv.Atoi(strings.TrimSpace(o.GetProperty(key, "")))
+	if err != nil {
+
+		return def
+	}
+	return int32(v)
+}
+
 // GetTile : Get a tile value in the current layer
 func (t *Tilemap) GetTile(layer, x, y int32) int32 {
 
@@ -89,11 +177,68 @@ func (t *Tilemap) GetNumericProperty(key string, def int32) int32 {
 	return int32(v)
 }
 
-func parseCSV(data string) []int32 {
+// HasProperty : Tells if a property with the given key exists
+func (t *Tilemap) HasProperty(key string) bool {
+
+	for _, p := range t.properties {
+
+		if p.key == key {
+
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNumericProperty : Like GetNumericProperty, but returns
+// an error instead of a zero if the value is not a number
+func (t *Tilemap) ParseNumericProperty(key string) (int32, error) {
+
+	if !t.HasProperty(key) {
+
+		return 0, fmt.Errorf("missing property \"%s\"", key)
+	}
+
+	v, err := strconv.Atoi(strings.TrimSpace(t.GetProperty(key, "")))
+	if err != nil {
+
+		return 0, fmt.Errorf("property \"%s\" is not a number", key)
+	}
+	return int32(v), nil
+}
+
+// LayerCount : Number of layers
+func (t *Tilemap) LayerCount() int32 {
+
+	return int32(len(t.layers))
+}
+
+// LayerLength : Number of tiles actually stored in the
+// layer, which is not necessarily width*height if the
+// file is broken
+func (t *Tilemap) LayerLength(layerID uint32) int32 {
+
+	if layerID >= uint32(len(t.layers)) {
+
+		return 0
+	}
+	return int32(len(t.layers[layerID]))
+}
+
+// Warnings : Problems found while parsing that did not
+// stop the parsing
+func (t *Tilemap) Warnings() []string {
+
+	return t.warnings
+}
+
+// Returns the tiles and the cells that could not be parsed
+func parseCSV(data string) ([]int32, []string) {
 
 	reader := csv.NewReader(strings.NewReader(data))
 
 	out := make([]int32, 0)
+	bad := make([]string, 0)
 
 	var line []string
 	var err error
@@ -108,18 +253,24 @@ func parseCSV(data string) []int32 {
 			break
 		}
 
-		for _, s := range line {
+		for i, s := range line {
 
 			v, err = strconv.Atoi(s)
 			if err == nil {
 
 				out = append(out, int32(v))
+
+			} else if s != "" || i < len(line)-1 {
+
+				// Tiled ends the rows with a comma,
+				// the empty field after it is fine
+				bad = append(bad, s)
 			}
 
 		}
 	}
 
-	return out
+	return out, bad
 }
 
 // CloneLayer : Clones a layer and returns an array
@@ -149,6 +300,7 @@ func ParseTMX(fpath string) (*Tilemap, error) {
 	t := new(Tilemap)
 	t.layers = make([]layer, 0)
 	t.properties = make([]keyValuePair, 0)
+	t.warnings = make([]string, 0)
 
 	file, err := os.Open(fpath)
 	if err != nil {
@@ -165,15 +317,48 @@ func ParseTMX(fpath string) (*Tilemap, error) {
 
 	// Parse XML
 	var mapXML tmx
-	xml.Unmarshal(byteValue, &mapXML)
+	xmlErr := xml.Unmarshal(byteValue, &mapXML)
+	if xmlErr != nil {
+
+		t.warnings = append(t.warnings, "invalid XML: "+xmlErr.Error())
+	}
 
-	for _, l := range mapXML.Layers {
+	var data []int32
+	var bad []string
+	for i, l := range mapXML.Layers {
 
-		t.layers = append(t.layers, parseCSV(l.Data))
+		data, bad = parseCSV(l.Data)
+		t.layers = append(t.layers, data)
 
+		for _, b := range bad {
+
+			t.warnings = append(t.warnings,
+				fmt.Sprintf("layer %d: ignored an invalid tile \"%s\"", i, b))
+		}
 	}
 	t.width = mapXML.Width
 	t.height = mapXML.Height
+	t.tileWidth = mapXML.TileWidth
+	t.tileHeight = mapXML.TileHeight
+
+	t.objects = make([]TilemapObject, 0)
+	var obj TilemapObject
+	for _, g := range mapXML.ObjectGroups {
+
+		for _, o := range g.Objects {
+
+			obj = TilemapObject{Name: o.Name, Type: o.Type,
+				X: o.X, Y: o.Y, Width: o.Width, Height: o.Height}
+
+			obj.properties = make([]keyValuePair, 0)
+			for _, p := range o.Properties.Properties {
+
+				obj.properties = append(obj.properties,
+					keyValuePair{key: p.Name, value: p.Value})
+			}
+			t.objects = append(t.objects, obj)
+		}
+	}
 
 	for _, p := range mapXML.Properties.Properties {
 
@@ -0,0 +1,140 @@
+package core
+
+import "sort"
+
+// VirtualEventType : The kind of a synthetic input event
+type VirtualEventType int32
+
+// Virtual event types
+const (
+	VirtualKeyPress      VirtualEventType = 0
+	VirtualKeyRelease    VirtualEventType = 1
+	VirtualButtonPress   VirtualEventType = 2
+	VirtualButtonRelease VirtualEventType = 3
+	VirtualAxisMovement  VirtualEventType = 4
+)
+
+// VirtualEvent : A synthetic input event that is fed
+// to the input manager as if it came from a device
+type VirtualEvent struct {
+	Type  VirtualEventType
+	Index uint32  // Scancode, button or axis
+	Value float32 // Axis position, ignored otherwise
+}
+
+type queuedEvent struct {
+	tick  uint64
+	event VirtualEvent
+}
+
+// Applies the queued events that are due. Called at the
+// end of refresh, which is where the events from the
+// devices arrive, too
+func (input *InputManager) flushVirtualEvents() {
+
+	count := 0
+	for _, q := range input.queue {
+
+		if q.tick > input.tick {
+			break
+		}
+
+		switch q.event.Type {
+
+		case VirtualKeyPress:
+			input.keyPressed(q.event.Index)
+			break
+
+		case VirtualKeyRelease:
+			input.keyReleased(q.event.Index)
+			break
+
+		case VirtualButtonPress:
+			input.joyButtonPressed(q.event.Index)
+			break
+
+		case VirtualButtonRelease:
+			input.joyButtonReleased(q.event.Index)
+			break
+
+		case VirtualAxisMovement:
+			input.joyAxisMovement(q.event.Index, q.event.Value)
+			break
+
+		default:
+			break
+		}
+		count++
+	}
+	input.queue = input.queue[count:]
+}
+
+// QueueEvent : Queue a synthetic event to arrive after the
+// given number of ticks. With zero delay the event arrives
+// with the next events from the devices, that is, the
+// scenes can see the key state on the next tick and the
+// action state on the tick after that, exactly like with
+// a real key press
+func (input *InputManager) QueueEvent(delay uint32, ev VirtualEvent) {
+
+	q := queuedEvent{tick: input.tick + uint64(delay) + 1, event: ev}
+
+	// Keep the queue in order, events for the same
+	// tick in the order they were queued
+	i := sort.Search(len(input.queue), func(i int) bool {
+
+		return input.queue[i].tick > q.tick
+	})
+	input.queue = append(input.queue, queuedEvent{})
+	copy(input.queue[i+1:], input.queue[i:])
+	input.queue[i] = q
+}
+
+// QueueKeyTap : Press a key after the delay and
+// release it after the given duration
+func (input *InputManager) QueueKeyTap(delay, duration uint32, scancode uint32) {
+
+	input.QueueEvent(delay, VirtualEvent{Type: VirtualKeyPress, Index: scancode})
+	input.QueueEvent(delay+MaxUInt32(1, duration),
+		VirtualEvent{Type: VirtualKeyRelease, Index: scancode})
+}
+
+// QueueButtonTap : Same as QueueKeyTap, but for
+// joystick buttons
+func (input *InputManager) QueueButtonTap(delay, duration uint32, button uint32) {
+
+	input.QueueEvent(delay, VirtualEvent{Type: VirtualButtonPress, Index: button})
+	input.QueueEvent(delay+MaxUInt32(1, duration),
+		VirtualEvent{Type: VirtualButtonRelease, Index: button})
+}
+
+// QueueAxisTilt : Tilt a joystick axis after the delay and
+// center it after the given duration
+func (input *InputManager) QueueAxisTilt(delay, duration uint32, axis uint32, value float32) {
+
+	input.QueueEvent(delay, VirtualEvent{Type: VirtualAxisMovement,
+		Index: axis, Value: value})
+	input.QueueEvent(delay+MaxUInt32(1, duration),
+		VirtualEvent{Type: VirtualAxisMovement, Index: axis, Value: 0.0})
+}
+
+// PendingEvents : Number of queued events that
+// have not arrived yet
+func (input *InputManager) PendingEvents() int32 {
+
+	return int32(len(input.queue))
+}
+
+// ClearEventQueue : Drop the events that have
+// not arrived yet
+func (input *InputManager) ClearEventQueue() {
+
+	input.queue = input.queue[:0]
+}
+
+// Ticks : How many times the input manager has
+// been refreshed
+func (input *InputManager) Ticks() uint64 {
+
+	return input.tick
+}
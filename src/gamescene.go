@@ -2,9 +2,11 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/jani-nykanen/blocked/src/core"
+	"github.com/jani-nykanen/blocked/src/puzzle"
 )
 
 const (
@@ -26,6 +28,16 @@ type gameScene struct {
 	clearMenu       *menu
 	settingsScreen  *settings
 	cinfo           *completionInfo
+	hints           *hintSolver
+	hintBoard       *puzzle.Board // The board the hint was asked for
+	hintReady       bool
+	hintDir         puzzle.Direction
+	hintMessage     string
+	hintWave        float32
+	usedHints       bool
+	recorder        *replay
+	actionNames     []string
+	playback        bool // Driven by the replay viewer
 }
 
 func (game *gameScene) createPauseMenu() {
@@ -35,6 +47,14 @@ func (game *gameScene) createPauseMenu() {
 		newMenuButton("Resume", func(self *menuButton, dir int32, ev *core.Event) {
 			game.pauseMenu.deactivate()
 		}, false),
+		newMenuButton("Undo", func(self *menuButton, dir int32, ev *core.Event) {
+			game.undo(ev)
+			game.pauseMenu.deactivate()
+		}, false),
+		newMenuButton("Redo", func(self *menuButton, dir int32, ev *core.Event) {
+			game.redo(ev)
+			game.pauseMenu.deactivate()
+		}, false),
 		newMenuButton("Reset", func(self *menuButton, dir int32, ev *core.Event) {
 			game.reset(ev)
 			game.pauseMenu.deactivate()
@@ -107,10 +127,16 @@ func (game *gameScene) Activate(ev *core.Event, param interface{}) error {
 	game.objects = newObjectManager()
 	game.gameStage.parseObjects(game.objects)
 
+	game.actionNames = ev.Input.ActionNames()
+	game.startRecording()
+
 	game.cloudPos = 0
 	game.failureTimer = 0
 	game.failed = false
 	game.cleared = false
+	game.hints = newHintSolver()
+	game.hintBoard = nil
+	game.usedHints = false
 	game.cogSprite = core.NewSprite(48, 48)
 
 	game.frameTransition = core.NewTransitionManager()
@@ -133,6 +159,7 @@ func (game *gameScene) nextStage(ev *core.Event) {
 		ev.Terminate(err)
 		return
 	}
+	game.hints.clear()
 
 	game.resetEvent(false)
 }
@@ -151,6 +178,34 @@ func (game *gameScene) resetEvent(resetStage bool) {
 	game.failed = false
 	game.cleared = false
 	game.failureTimer = 0
+
+	game.hintBoard = nil
+	game.usedHints = false
+
+	game.startRecording()
+}
+
+func (game *gameScene) startRecording() {
+
+	if game.playback {
+
+		return
+	}
+	game.recorder = newReplay(game.gameStage.id, game.actionNames)
+}
+
+func (game *gameScene) saveReplay() {
+
+	if game.recorder == nil {
+
+		return
+	}
+
+	_, err := game.recorder.save()
+	if err != nil {
+
+		fmt.Printf("Error writing the replay file: %s\n", err.Error())
+	}
 }
 
 func (game *gameScene) updateBackground(step int32) {
@@ -176,15 +231,106 @@ func (game *gameScene) reset(ev *core.Event) {
 
 }
 
+func (game *gameScene) undo(ev *core.Event) {
+
+	if !game.objects.undo() {
+
+		return
+	}
+	ev.Audio.PlaySample(ev.Assets.GetAsset("cancel").(*core.Sample), 40)
+
+	// Undoing the move that caused the failure
+	if game.failed {
+
+		game.failed = false
+		game.failureTimer = 0
+		game.gameStage.shake(0)
+	}
+}
+
+func (game *gameScene) redo(ev *core.Event) {
+
+	if game.objects.redo() {
+
+		ev.Audio.PlaySample(ev.Assets.GetAsset("next").(*core.Sample), 40)
+	}
+}
+
+func (game *gameScene) showHint(ev *core.Event) {
+
+	if game.objects.result != nil || game.objects.board == game.hintBoard {
+
+		return
+	}
+
+	game.hintBoard = game.objects.board
+	game.hintReady = false
+	game.hintDir = puzzle.DirNone
+	game.hintMessage = "Thinking..."
+	game.hintWave = 0
+
+	if h, ok := game.hints.request(game.hintBoard); ok {
+
+		game.setHint(h, ev)
+	}
+}
+
+func (game *gameScene) setHint(h hint, ev *core.Event) {
+
+	game.hintReady = true
+	game.hintDir = h.dir
+	game.hintMessage = h.message
+	if h.solved {
+
+		game.usedHints = true
+	}
+
+	ev.Audio.PlaySample(ev.Assets.GetAsset("next").(*core.Sample), 40)
+}
+
+func (game *gameScene) updateHint(ev *core.Event) {
+
+	const waveSpeed float32 = 0.1
+
+	game.hints.poll()
+
+	// Only valid as long as nothing moves
+	if game.objects.board != game.hintBoard ||
+		game.objects.result != nil {
+
+		game.hintBoard = nil
+		return
+	}
+
+	if !game.hintReady {
+
+		if h, ok := game.hints.lookup(game.hintBoard); ok {
+
+			game.setHint(h, ev)
+		}
+	}
+
+	game.hintWave = float32(
+		math.Mod(float64(game.hintWave+waveSpeed*float32(ev.Step())), math.Pi*2))
+}
+
 func (game *gameScene) Refresh(ev *core.Event) {
 
 	const failTime int32 = 60
+	const wallShakeTime int32 = 15
 	const clearTimerSpeed int32 = 2
 
 	if ev.Transition.Active() {
 		return
 	}
 
+	// Only the ticks that may consume input are recorded,
+	// so that the replay starts from a fresh stage
+	if game.recorder != nil && !game.frameTransition.Active() {
+
+		game.recorder.record(ev.Input)
+	}
+
 	if game.settingsScreen.active() {
 
 		game.settingsScreen.update(ev)
@@ -252,14 +398,39 @@ func (game *gameScene) Refresh(ev *core.Event) {
 			return
 		}
 
-		if game.objects.update(game.gameStage, ev) {
+		if !game.cleared {
+
+			if ev.Input.GetActionState("undo") == core.StatePressed {
+
+				game.undo(ev)
+
+			} else if ev.Input.GetActionState("redo") == core.StatePressed {
+
+				game.redo(ev)
+
+			} else if ev.Input.GetActionState("hint") == core.StatePressed {
+
+				game.showHint(ev)
+			}
+		}
+
+		if game.objects.update(ev) {
 
 			game.failed = true
 			game.failureTimer = failTime
 
 			game.gameStage.shake(failTime)
+
+			game.saveReplay()
 		}
 
+		if game.objects.wallBroken {
+
+			game.objects.wallBroken = false
+			game.gameStage.shake(wallShakeTime)
+		}
+		game.updateHint(ev)
+
 		game.cleared = game.objects.cleared || game.cleared
 
 		if game.cleared && !game.clearMenu.active {
@@ -275,13 +446,21 @@ func (game *gameScene) Refresh(ev *core.Event) {
 
 				state = 2
 			}
-			game.cinfo.updateState(game.gameStage.id, state)
+			game.cinfo.updateState(game.gameStage.id, state, game.usedHints)
 
 			game.endingAchieved = game.cinfo.checkIfNewEndingObtained()
+
+			game.saveReplay()
 		}
 
 	} else {
 
+		if ev.Input.GetActionState("undo") == core.StatePressed {
+
+			game.undo(ev)
+			return
+		}
+
 		game.failureTimer -= ev.Step()
 
 		if game.failureTimer <= 0 {
@@ -343,6 +522,10 @@ func (game *gameScene) drawHUD(c *core.Canvas, ap *core.AssetPack) {
 
 	// Beautiful...
 	moveStrLeft := "Moves: "
+	if game.objects.board.Ruleset() == puzzle.RulesetStep {
+
+		moveStrLeft = "Steps: "
+	}
 	moveStrMiddle := strconv.Itoa(int(game.objects.moveCount))
 	moveStrRight := "(" + string(rune(5)) +
 		strconv.Itoa(int(game.gameStage.bonusMoveLimit)) +
@@ -403,6 +586,90 @@ func (game *gameScene) drawHUD(c *core.Canvas, ap *core.AssetPack) {
 			c.Viewport().W-moveXOff-6+int32(len(moveStrLeft+moveStrMiddle))*8,
 			c.Viewport().H-12,
 			0, 0, false)
+
+		// The color that moves
+		if game.objects.board.GroupMode() {
+
+			c.DrawText(bmpFont, "Color:",
+				c.Viewport().W/2-28, c.Viewport().H-12, -1, 0, false)
+		}
+	}
+
+	if game.objects.board.GroupMode() {
+
+		c.DrawBitmapRegion(ap.GetAsset("blocks").(*core.Bitmap),
+			game.objects.board.Active()*16, 0, 16, 16,
+			c.Viewport().W/2+14, c.Viewport().H-16, core.FlipNone)
+	}
+}
+
+func (game *gameScene) drawHint(c *core.Canvas, ap *core.AssetPack) {
+
+	const shaftLength int32 = 12
+	const headLength int32 = 8
+	const amplitude float32 = 2.0
+
+	if game.hintBoard == nil {
+		return
+	}
+
+	cx := c.Viewport().W / 2
+	cy := c.Viewport().H / 2
+
+	if game.hintDir == puzzle.DirNone {
+
+		c.DrawText(ap.GetAsset("font").(*core.Bitmap), game.hintMessage,
+			cx, cy-4, 0, 0, true)
+		return
+	}
+
+	dx, dy := game.hintDir.Delta()
+	wave := core.RoundFloat32(float32(math.Sin(float64(game.hintWave))) * amplitude)
+
+	cx += dx * wave
+	cy += dy * wave
+
+	// The arrow is defined pointing right, this
+	// turns it to the hint direction
+	fill := func(x, y, w, h int32, col core.Color) {
+
+		switch game.hintDir {
+
+		case puzzle.DirLeft:
+			c.FillRect(cx-x-w, cy+y, w, h, col)
+			break
+
+		case puzzle.DirUp:
+			c.FillRect(cx+y, cy-x-w, h, w, col)
+			break
+
+		case puzzle.DirDown:
+			c.FillRect(cx+y, cy+x, h, w, col)
+			break
+
+		default:
+			c.FillRect(cx+x, cy+y, w, h, col)
+			break
+		}
+	}
+
+	colors := []core.Color{
+		core.NewRGB(0, 0, 0),
+		core.NewRGB(255, 255, 0),
+	}
+
+	// Outlines first, then the arrow itself
+	var h, o int32
+	for i := int32(0); i < 2; i++ {
+
+		o = 1 - i
+
+		fill(-shaftLength-o, -2-o, shaftLength+o, 4+o*2, colors[i])
+		for x := -o; x < headLength+o; x++ {
+
+			h = headLength + o - core.MaxInt32(x, 0)
+			fill(x, -h, 1, h*2, colors[i])
+		}
 	}
 }
 
@@ -421,7 +688,8 @@ func (game *gameScene) drawSuccess(c *core.Canvas, ap *core.AssetPack) {
 		core.FlipNone)
 
 	sx := int32(0)
-	if game.objects.moveCount <= game.gameStage.bonusMoveLimit {
+	if game.objects.moveCount <= game.gameStage.bonusMoveLimit &&
+		!game.usedHints {
 
 		sx = 24
 	}
@@ -447,6 +715,7 @@ func (game *gameScene) Redraw(c *core.Canvas, ap *core.AssetPack) {
 	// This needs to be called before anything else because when
 	// (re)starting the stage, calling this after background
 	// drawing will cause one frame of weirdness
+	game.gameStage.syncTiles(game.objects)
 	game.gameStage.preDraw(c, ap)
 
 	game.drawBackground(c, ap.GetAsset("background").(*core.Bitmap))
@@ -465,6 +734,8 @@ func (game *gameScene) Redraw(c *core.Canvas, ap *core.AssetPack) {
 	game.objects.draw(c, ap)
 	game.gameStage.postDraw(c, ap)
 
+	game.drawHint(c, ap)
+
 	if game.cleared && !game.endingAchieved {
 
 		game.drawSuccess(c, ap)
@@ -492,6 +763,12 @@ func (game *gameScene) Dispose() interface{} {
 
 	game.gameStage.dispose()
 
+	// The viewer uses a throwaway completion info
+	if game.playback {
+
+		return nil
+	}
+
 	err := game.cinfo.saveToFile(defaultSaveFilePath)
 	if err != nil {
 
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/jani-nykanen/blocked/src/puzzle"
+)
+
+const (
+	// Enough for every stage we have, a few times over
+	hintStateLimit int32 = 50000
+)
+
+type hint struct {
+	dir     puzzle.Direction
+	message string // Shown if there is no direction
+	solved  bool
+}
+
+type hintResult struct {
+	key        string
+	generation int32
+	value      hint
+}
+
+// Runs the solver in the background so that a slow
+// search does not stall the frame, and remembers the
+// hints computed for each board
+type hintSolver struct {
+	cache      map[string]hint
+	results    chan hintResult
+	busy       bool
+	next       *puzzle.Board // Waiting for the current search to end
+	generation int32
+}
+
+func newHintSolver() *hintSolver {
+
+	hs := new(hintSolver)
+	hs.cache = make(map[string]hint)
+	// Buffered, so that a search never blocks if its
+	// result is not polled anymore
+	hs.results = make(chan hintResult, 1)
+
+	return hs
+}
+
+func solveHint(board *puzzle.Board) hint {
+
+	sol, err := puzzle.Solve(board, hintStateLimit)
+	if err == puzzle.ErrNoSolution {
+
+		return hint{dir: puzzle.DirNone, message: "No way out!"}
+
+	} else if err != nil || len(sol.Moves) == 0 {
+
+		return hint{dir: puzzle.DirNone, message: "No idea, sorry"}
+	}
+
+	// The solution may start with another color
+	if sol.Groups != nil && sol.Groups[0] != board.Active() {
+
+		return hint{dir: puzzle.DirNone, message: "Change the color", solved: true}
+	}
+
+	return hint{dir: sol.Moves[0], solved: true}
+}
+
+// The active color is not a part of the board key, but
+// the hint depends on it
+func hintKey(board *puzzle.Board) string {
+
+	return board.Key() + "/" + strconv.Itoa(int(board.Active()))
+}
+
+func (hs *hintSolver) start(board *puzzle.Board) {
+
+	key := hintKey(board)
+	generation := hs.generation
+
+	hs.busy = true
+	go func() {
+
+		hs.results <- hintResult{
+			key:        key,
+			generation: generation,
+			value:      solveHint(board)}
+	}()
+}
+
+func (hs *hintSolver) lookup(board *puzzle.Board) (hint, bool) {
+
+	h, ok := hs.cache[hintKey(board)]
+	return h, ok
+}
+
+// Returns the hint of the board if it is known,
+// otherwise starts computing it
+func (hs *hintSolver) request(board *puzzle.Board) (hint, bool) {
+
+	if h, ok := hs.lookup(board); ok {
+
+		return h, true
+	}
+
+	if hs.busy {
+
+		hs.next = board
+
+	} else {
+
+		hs.start(board)
+	}
+	return hint{}, false
+}
+
+// Picks up the result of the search if it has ended.
+// Should be called every frame
+func (hs *hintSolver) poll() {
+
+	if !hs.busy {
+		return
+	}
+
+	select {
+
+	case res := <-hs.results:
+
+		hs.busy = false
+		if res.generation == hs.generation {
+
+			hs.cache[res.key] = res.value
+		}
+		if hs.next != nil {
+
+			board := hs.next
+			hs.next = nil
+
+			if _, ok := hs.lookup(board); !ok {
+
+				hs.start(board)
+			}
+		}
+		break
+
+	default:
+		break
+	}
+}
+
+// The boards of different stages can have the same
+// key, so the cache must be emptied when the stage
+// changes. A search still running is left to finish,
+// but its result is thrown away
+func (hs *hintSolver) clear() {
+
+	hs.cache = make(map[string]hint)
+	hs.next = nil
+	hs.generation++
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -14,6 +15,9 @@ func main() {
 	var err error
 	var win *core.GameWindow
 
+	replayPath := flag.String("replay", "", "play back a replay file instead of the game")
+	flag.Parse()
+
 	err = core.InitSystem()
 	if err != nil {
 
@@ -83,7 +87,13 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = win.Launch(newIntroScene())
+	initialScene := newIntroScene()
+	if *replayPath != "" {
+
+		initialScene = newReplayScene(*replayPath)
+	}
+
+	err = win.Launch(initialScene)
 	if err != nil {
 
 		fmt.Println(err)
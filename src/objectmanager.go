@@ -5,30 +5,156 @@ import (
 	"math/rand"
 
 	"github.com/jani-nykanen/blocked/src/core"
+	"github.com/jani-nykanen/blocked/src/puzzle"
 )
 
+// The frame of the bombs in blocks.png
+const bombFrame int32 = 5
+
+// A snapshot after a settled move
+type historyEntry struct {
+	board      *puzzle.Board
+	blockCount int32
+	moveCount  int32
+}
+
 type objectManager struct {
 	blocks       [](*block)
+	pieces       [][]int32 // The blocks that draw each cell of a board block
 	fragments    [](*fragment)
+	debris       [](*fragment) // Pieces of broken walls, from the tileset
+	wallBroken   bool
+	board        *puzzle.Board
+	tiles        []int32 // As they should look right now
+	capacity     []int32 // Same for the numbers on the holes
+	tilesChanged bool
+	result       *puzzle.Result // The move being animated, if any
+	step         int32
+	history      []historyEntry
+	historyPos   int32
 	failurePoint core.Point
 	blockCount   int32
 	moveCount    int32
+	failed       bool
 	cleared      bool
 }
 
-func (objm *objectManager) addBlock(x, y, id int32) {
+func (objm *objectManager) createBlocks(board *puzzle.Board) {
 
-	objm.blocks = append(objm.blocks, newBlock(x, y, id))
+	objm.blocks = make([](*block), 0)
+	objm.pieces = make([][]int32, 0)
 
-	if id > 0 {
+	// Indices of the pieces must match the ones
+	// in the board. Each cell is a block of its own
+	var b *block
+	var cells []int32
+	for _, pb := range board.Blocks() {
 
-		objm.blockCount++
+		cells = make([]int32, 0, 1)
+		for _, p := range pb.Footprint() {
+
+			b = newBlock(p.X, p.Y, pb.ID, int32(pb.Variant))
+			b.exist = pb.Exist
+			if pb.Bomb {
+
+				b.spr.SetFrame(bombFrame, b.spr.Row())
+			}
+
+			cells = append(cells, int32(len(objm.blocks)))
+			objm.blocks = append(objm.blocks, b)
+		}
+		objm.pieces = append(objm.pieces, cells)
 	}
 }
 
-func (objm *objectManager) nextFragment() *fragment {
+func (objm *objectManager) setBoard(board *puzzle.Board) {
 
-	for _, f := range objm.fragments {
+	objm.board = board
+	objm.result = nil
+
+	objm.createBlocks(board)
+	objm.blockCount = board.BlockCount()
+
+	objm.tiles = board.Tiles()
+	objm.capacity = board.Capacities()
+	objm.tilesChanged = true
+
+	objm.history = []historyEntry{{board: board,
+		blockCount: objm.blockCount, moveCount: objm.moveCount}}
+	objm.historyPos = 0
+}
+
+func (objm *objectManager) pushHistory() {
+
+	// Making a new move forgets the moves that were undone
+	objm.history = append(objm.history[:objm.historyPos+1],
+		historyEntry{board: objm.board,
+			blockCount: objm.blockCount, moveCount: objm.moveCount})
+	objm.historyPos++
+}
+
+func (objm *objectManager) restore(e historyEntry) {
+
+	objm.board = e.board
+	objm.result = nil
+
+	objm.tiles = e.board.Tiles()
+	objm.capacity = e.board.Capacities()
+	objm.tilesChanged = true
+
+	objm.createBlocks(e.board)
+	objm.blockCount = e.blockCount
+	objm.moveCount = e.moveCount
+
+	objm.failed = false
+	objm.cleared = false
+}
+
+// Returns false if there is nothing to undo
+func (objm *objectManager) undo() bool {
+
+	if objm.cleared || objm.result != nil {
+
+		return false
+	}
+
+	// The failed move was never stored, so
+	// the latest entry is the one before it
+	if objm.failed {
+
+		objm.restore(objm.history[objm.historyPos])
+		return true
+	}
+
+	if objm.historyPos <= 0 {
+
+		return false
+	}
+
+	objm.historyPos--
+	objm.restore(objm.history[objm.historyPos])
+
+	return true
+}
+
+// Returns false if there is nothing to redo
+func (objm *objectManager) redo() bool {
+
+	if objm.cleared || objm.failed || objm.result != nil ||
+		objm.historyPos >= int32(len(objm.history))-1 {
+
+		return false
+	}
+
+	objm.historyPos++
+	objm.restore(objm.history[objm.historyPos])
+
+	return true
+}
+
+func nextFragment(pool *[](*fragment)) *fragment {
+
+	for _, f := range *pool {
 
 		if !f.exist {
 
@@ -36,25 +162,36 @@ func (objm *objectManager) nextFragment() *fragment {
 		}
 	}
 
-	objm.fragments = append(objm.fragments, newFragment())
+	*pool = append(*pool, newFragment())
 
-	return objm.fragments[len(objm.fragments)-1]
+	return (*pool)[len(*pool)-1]
 }
 
 func (objm *objectManager) createFragments(b *block) {
 
+	objm.burst(&objm.fragments,
+		b.pos.X*16+b.spr.Width()/2, b.pos.Y*16+b.spr.Width()/2,
+		b.spr.Frame()*b.spr.Width(), b.spr.Row()*b.spr.Height(),
+		b.spr.Width(), b.spr.Height())
+}
+
+// The wall tile is the top-left one in the tileset
+func (objm *objectManager) createDebris(p puzzle.Point) {
+
+	objm.burst(&objm.debris, p.X*16+8, p.Y*16+8, 0, 0, 16, 16)
+}
+
+// Splits the given area of a bitmap to 4x4 fragments
+// flying away from the center
+func (objm *objectManager) burst(pool *[](*fragment),
+	px, py, sx, sy, w, h int32) {
+
 	const minSpeed = 2.0
 	const maxSpeed = 3.0
 	const fragmentTime = 30
 
-	px := b.pos.X*16 + b.spr.Width()/2
-	py := b.pos.Y*16 + b.spr.Width()/2
-
-	sw := b.spr.Width() / 4
-	sh := b.spr.Height() / 4
-
-	sx := b.spr.Frame() * b.spr.Width()
-	sy := b.spr.Row() * b.spr.Height()
+	sw := w / 4
+	sh := h / 4
 
 	var angle float64
 	var speed float64
@@ -68,7 +205,7 @@ func (objm *objectManager) createFragments(b *block) {
 			dist = math.Hypot(float64(x)-1.5, float64(y)-1.5) / math.Sqrt2
 
 			speed = rand.Float64()*(maxSpeed-minSpeed) + minSpeed
-			objm.nextFragment().spawn(px, py,
+			nextFragment(pool).spawn(px, py,
 				sx+x*sw, sy+y*sh, sw, sh,
 				float32(math.Cos(angle)*speed*dist),
 				float32(math.Sin(angle)*speed*dist),
@@ -90,82 +227,213 @@ func (objm *objectManager) isAnyMoving() bool {
 	return false
 }
 
-func (objm *objectManager) update(s *stage, ev *core.Event) bool {
+// Holding a direction down keeps making moves, unless
+// every press is a single step
+func (objm *objectManager) directionActive(ev *core.Event, action string) bool {
 
-	loop := true
-	increaseMovementCounter := false
+	state := ev.Input.GetActionState(action)
+	if objm.board.Ruleset() == puzzle.RulesetStep {
 
-	notMoving := !objm.isAnyMoving()
+		return state == core.StatePressed
+	}
+	return state&core.StateDownOrPressed == 1
+}
 
-	// All these loops are required to make it
-	// possible to move several blocks at the
-	// same time "consistently"
-	if !objm.cleared && notMoving {
+func (objm *objectManager) handleControls(ev *core.Event) {
 
-		for {
+	dir := puzzle.DirNone
+	if objm.directionActive(ev, "left") {
 
-			loop = false
-			for _, b := range objm.blocks {
+		dir = puzzle.DirLeft
 
-				if b.handleControls(s, ev) {
+	} else if objm.directionActive(ev, "right") {
 
-					loop = true
-					increaseMovementCounter = true
-				}
-			}
-			if !loop {
-				break
-			}
-		}
+		dir = puzzle.DirRight
+
+	} else if objm.directionActive(ev, "up") {
+
+		dir = puzzle.DirUp
+
+	} else if objm.directionActive(ev, "down") {
+
+		dir = puzzle.DirDown
+	}
+
+	if dir == puzzle.DirNone &&
+		ev.Input.GetActionState("cycle") == core.StatePressed {
+
+		objm.cycle(ev)
+		return
 	}
 
-	if increaseMovementCounter {
+	res := objm.board.Apply(dir)
+	if res.Outcome == puzzle.OutcomeNone {
 
-		objm.moveCount++
+		return
 	}
 
-	var state int32
-	anyMoving := objm.isAnyMoving()
-	/*
-		if notMoving && anyMoving {
+	objm.result = res
+	objm.step = 0
+	objm.moveCount++
+
+	objm.startStep()
+}
+
+// Changes the color of the blocks that move. It is not a
+// move, so the history entry of the board is replaced
+func (objm *objectManager) cycle(ev *core.Event) {
+
+	if !objm.board.GroupMode() || objm.failed {
 
-			ev.Audio.PlaySample(ev.Assets.GetAsset("move").(*core.Sample),
-				40)
+		return
+	}
+
+	board := objm.board.Cycle()
+	if board.Active() == objm.board.Active() {
+
+		return
+	}
+	objm.board = board
+	objm.history[objm.historyPos].board = board
+
+	ev.Audio.PlaySample(ev.Assets.GetAsset("next").(*core.Sample), 40)
+}
+
+func (objm *objectManager) startStep() {
+
+	var parts []puzzle.Move
+	for _, m := range objm.result.Steps[objm.step].Moves {
+
+		parts = m.Parts
+		if len(parts) == 0 {
+
+			parts = []puzzle.Move{m}
 		}
-	*/
-	playDestroy := false
+
+		for k, i := range objm.pieces[m.Block] {
+
+			objm.blocks[i].moveTo(parts[k])
+		}
+	}
+}
+
+func (objm *objectManager) fillHole(p core.Point) {
+
+	k := p.Y*objm.board.Width() + p.X
+	if objm.capacity[k] > 0 {
+
+		objm.capacity[k]--
+		objm.tilesChanged = true
+	}
+}
+
+// Neutral blocks painted with a color need to find a
+// hole, too, and the ones painted neutral do not
+func (objm *objectManager) paintBlock(i, id int32) {
+
+	old := objm.blocks[objm.pieces[i][0]].id
+	if old == 0 && id > 0 {
+
+		objm.blockCount++
+
+	} else if old > 0 && id == 0 {
+
+		objm.blockCount--
+	}
+
+	for _, j := range objm.pieces[i] {
+
+		objm.blocks[j].paint(id)
+	}
+}
+
+// Returns true if a block dropped to a wrong hole
+func (objm *objectManager) finishStep(ev *core.Event) bool {
+
+	var b *block
+
 	playHit := false
-	for _, b := range objm.blocks {
+	playDestroy := false
+	playPaint := false
 
-		// This is required to make sure the "destroy" sound
-		// won't cut too early
-		if b.playDestroy {
+	for _, m := range objm.result.Steps[objm.step].Moves {
 
-			playDestroy = true
+		if m.Painted {
+
+			objm.paintBlock(m.Block, m.ID)
+			playPaint = true
 		}
 
-		state = b.update(anyMoving, s, ev)
+		switch m.Event {
+
+		case puzzle.EventStopped:
+
+			playHit = true
+			break
 
-		if state == blockRightHole {
+		case puzzle.EventCleared:
 
-			objm.createFragments(b)
 			objm.blockCount--
+			playDestroy = true
+			break
 
-		} else if state == blockWrongHole {
+		case puzzle.EventExploded:
 
-			b.computeRenderingPosition()
-			objm.failurePoint = b.renderPos
+			objm.wallBroken = true
+			playDestroy = true
+			break
 
-			objm.failurePoint.X += 8
-			objm.failurePoint.Y += 8
+		case puzzle.EventPlugged:
 
-			return true
+			playHit = true
+			break
+
+		default:
+			break
+		}
+
+		if m.Event == puzzle.EventNone {
+			continue
 		}
 
-		if b.playHit {
+		for _, i := range objm.pieces[m.Block] {
 
-			playHit = true
+			b = objm.blocks[i]
+			b.stop()
+
+			if m.Event == puzzle.EventCleared {
+
+				b.exist = false
+				objm.createFragments(b)
+				objm.fillHole(b.pos)
+
+			} else if m.Event == puzzle.EventExploded {
+
+				b.exist = false
+				objm.createFragments(b)
+
+			} else if m.Event == puzzle.EventPlugged {
+
+				// The stage draws the sunk block
+				b.exist = false
+				objm.capacity[b.pos.Y*objm.board.Width()+b.pos.X] = 0
+			}
+		}
+	}
+
+	var k int32
+	step := objm.result.Steps[objm.step]
+	for _, t := range step.Tiles {
+
+		k = t.Pos.Y*objm.board.Width() + t.Pos.X
+		if objm.tiles[k] == puzzle.TileCrackedWall || objm.tiles[k] == puzzle.TileWall {
+
+			objm.createDebris(t.Pos)
+			objm.wallBroken = true
 		}
+
+		objm.tiles[k] = t.Tile
+		objm.tilesChanged = true
 	}
 
 	if playHit {
@@ -180,30 +448,104 @@ func (objm *objectManager) update(s *stage, ev *core.Event) bool {
 			40)
 	}
 
-	for _, f := range objm.fragments {
+	if playPaint {
 
-		f.update(ev)
+		ev.Audio.PlaySample(ev.Assets.GetAsset("paint").(*core.Sample),
+			40)
+	}
+
+	objm.step++
+	if objm.step < int32(len(objm.result.Steps)) {
+
+		objm.startStep()
+		return false
+	}
+
+	// The move has been resolved
+	objm.failed = objm.result.Outcome == puzzle.OutcomeFailed ||
+		objm.result.Outcome == puzzle.OutcomeLoop
+	if objm.failed {
+
+		// Blocks sliding forever count as a failure, too,
+		// even if none of them dropped to a hole
+		for _, b := range objm.blocks {
+
+			b.stop()
+		}
+
+		objm.failurePoint.X = objm.result.FailurePoint.X*16 + 8
+		objm.failurePoint.Y = objm.result.FailurePoint.Y*16 + 8
+
+		ev.Audio.PlaySample(ev.Assets.GetAsset("failure").(*core.Sample),
+			60)
+
+	} else {
+
+		objm.board = objm.result.Board
+		objm.blockCount = objm.board.BlockCount()
+		objm.cleared = objm.result.Outcome == puzzle.OutcomeCleared
+
+		objm.pushHistory()
+	}
+	objm.result = nil
+
+	return objm.failed
+}
+
+func (objm *objectManager) update(ev *core.Event) bool {
+
+	if !objm.cleared && objm.result == nil {
+
+		objm.handleControls(ev)
+	}
+
+	failed := false
+	if objm.result != nil {
+
+		for _, b := range objm.blocks {
+
+			b.handleMovement(ev)
+		}
+
+		// All the blocks move at the same speed, so
+		// every block in the step arrives at once
+		if !objm.isAnyMoving() {
+
+			failed = objm.finishStep(ev)
+		}
 	}
 
-	// To make sure blocks are not going to tiles
-	// that got reserved in the update loop, after
-	// the movement. To avoid "nudging" we call this
-	// afterwards
 	for _, b := range objm.blocks {
 
-		b.safeCheck(s)
+		b.computeRenderingPosition()
 	}
 
-	objm.cleared = objm.blockCount <= 0
+	for _, f := range objm.fragments {
 
-	return false
+		f.update(ev)
+	}
+	for _, f := range objm.debris {
+
+		f.update(ev)
+	}
+
+	return failed
 }
 
 func (objm *objectManager) drawOutlines(c *core.Canvas, ap *core.AssetPack) {
 
+	// In the group mode, the blocks that are
+	// going to move are highlighted
+	col := core.NewRGB(0, 0, 0)
 	for _, b := range objm.blocks {
 
-		b.drawOutlines(c, ap)
+		if objm.board != nil && objm.board.GroupMode() &&
+			b.id == objm.board.Active() {
+
+			b.drawOutlines(c, ap, core.NewRGB(255, 255, 85))
+			continue
+		}
+		b.drawOutlines(c, ap, col)
 	}
 }
 
@@ -222,20 +564,49 @@ func (objm *objectManager) draw(c *core.Canvas, ap *core.AssetPack) {
 		b.draw(c, ap)
 	}
 
+	// Join the cells of the multi-cell blocks
+	for _, p := range objm.pieces {
+
+		for _, i := range p {
+
+			for _, j := range p {
+
+				objm.blocks[i].drawJoint(c, ap, objm.blocks[j])
+			}
+		}
+	}
+
 	bmpBlocks := ap.GetAsset("blocks").(*core.Bitmap)
 	for _, f := range objm.fragments {
 
 		f.draw(c, bmpBlocks)
 	}
+
+	bmpTiles := ap.GetAsset("tileset").(*core.Bitmap)
+	for _, f := range objm.debris {
+
+		f.draw(c, bmpTiles)
+	}
 }
 
 func (objm *objectManager) clear() {
 
 	objm.blocks = make([](*block), 0)
+	objm.pieces = make([][]int32, 0)
 	objm.fragments = make([](*fragment), 0)
+	objm.debris = make([](*fragment), 0)
+
+	objm.board = nil
+	objm.result = nil
+
+	objm.history = make([]historyEntry, 0)
+	objm.historyPos = 0
 
 	objm.blockCount = 0
 	objm.moveCount = 0
+
+	objm.failed = false
+	objm.cleared = false
 }
 
 func newObjectManager() *objectManager {
@@ -243,11 +614,20 @@ func newObjectManager() *objectManager {
 	objm := new(objectManager)
 
 	objm.blocks = make([](*block), 0)
+	objm.pieces = make([][]int32, 0)
 	objm.fragments = make([](*fragment), 0)
+	objm.debris = make([](*fragment), 0)
+
+	objm.board = nil
+	objm.result = nil
+
+	objm.history = make([]historyEntry, 0)
+	objm.historyPos = 0
 
 	objm.blockCount = 0
 	objm.moveCount = 0
 
+	objm.failed = false
 	objm.cleared = false
 
 	return objm
@@ -0,0 +1,966 @@
+// Package puzzle contains the rules of the game
+// without any rendering or SDL dependencies, so
+// that the stages can be reasoned about by tools, too
+package puzzle
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tile IDs, as they appear in the TMX files
+const (
+	TileFloor        int32 = 0
+	TileWall         int32 = 1
+	TileHoleFirst    int32 = 2
+	TileHoleLast     int32 = 5
+	TileArrowLeft    int32 = 6
+	TileArrowRight   int32 = 7
+	TileArrowUp      int32 = 8
+	TileNeutralBlock int32 = 9
+	TileBlockFirst   int32 = 10
+	TileBlockLast    int32 = 13
+	TileArrowDown    int32 = 17
+	TileFenceLeft    int32 = 18 // Cannot be entered from the left
+	TileFenceRight   int32 = 19
+	TileFenceUp      int32 = 20
+	TileFenceDown    int32 = 21
+	TileSticky       int32 = 22 // Stops the blocks that enter it
+
+	// Teleporters with the same ID form a pair
+	TileTeleporterFirst int32 = 23
+	TileTeleporterLast  int32 = 26
+
+	// Gates of a channel are open while a block
+	// rests on any plate of the same channel
+	TilePlate      int32 = 27
+	TileGateClosed int32 = 28
+	TileGateOpen   int32 = 29
+
+	// Paint tiles turn the blocks that run over them
+	// to the color of the tile, the first one is neutral
+	TilePaintFirst int32 = 30
+	TilePaintLast  int32 = 34
+
+	// Turns to floor after the blocks have hit
+	// it a given number of times
+	TileCrackedWall int32 = 35
+
+	// Conveyors push the blocks that rest on them after
+	// every move, in the order left, right, up, down
+	TileConveyorFirst int32 = 36
+	TileConveyorLast  int32 = 39
+
+	// A neutral block that destroys the walls around
+	// it when it stops
+	TileBomb int32 = 40
+
+	// A hole a neutral block has sunk to, acts as floor.
+	// Only the game makes these, the stage files cannot
+	// have them
+	TilePlugged int32 = 41
+)
+
+// IsKnownTile : Tells if the game knows what to do
+// with the tile ID
+func IsKnownTile(tid int32) bool {
+
+	return (tid >= TileFloor && tid <= TileBlockLast) ||
+		(tid >= TileArrowDown && tid <= TileBomb)
+}
+
+// IsSolidTile : Tells if the blocks cannot enter the tile
+func IsSolidTile(tid int32) bool {
+
+	return tid == TileWall || tid == TileGateClosed || tid == TileCrackedWall
+}
+
+// IsTeleporter : Guess what
+func IsTeleporter(tid int32) bool {
+
+	return tid >= TileTeleporterFirst && tid <= TileTeleporterLast
+}
+
+// ConveyorDirection : The direction a conveyor tile pushes
+// the blocks to, or DirNone if the tile is not a conveyor
+func ConveyorDirection(tid int32) Direction {
+
+	if tid < TileConveyorFirst || tid > TileConveyorLast {
+
+		return DirNone
+	}
+	return Direction(tid-TileConveyorFirst) + DirLeft
+}
+
+// PaintID : The block ID a paint tile gives to the
+// blocks, or -1 if the tile is not a paint tile
+func PaintID(tid int32) int32 {
+
+	if tid < TilePaintFirst || tid > TilePaintLast {
+
+		return -1
+	}
+	return tid - TilePaintFirst
+}
+
+// TileName : A name for the kind of the tile, used
+// by tools to describe the stages
+func TileName(tid int32) string {
+
+	switch {
+
+	case tid == TileFloor:
+		return "floor"
+
+	case tid == TileWall:
+		return "wall"
+
+	case tid >= TileHoleFirst && tid <= TileHoleLast:
+		return "hole"
+
+	case tid >= TileNeutralBlock && tid <= TileBlockLast:
+		return "block"
+
+	case tid == TileBomb:
+		return "bomb"
+
+	case ArrowDirection(tid) != DirNone:
+		return "arrow"
+
+	case FenceSide(tid) != DirNone:
+		return "fence"
+
+	case tid == TileSticky:
+		return "sticky"
+
+	case IsTeleporter(tid):
+		return "teleporter"
+
+	case tid == TilePlate:
+		return "plate"
+
+	case tid == TileGateClosed || tid == TileGateOpen:
+		return "gate"
+
+	case PaintID(tid) >= 0:
+		return "paint"
+
+	case tid == TileCrackedWall:
+		return "cracked wall"
+
+	case ConveyorDirection(tid) != DirNone:
+		return "conveyor"
+
+	case tid == TilePlugged:
+		return "plugged hole"
+
+	default:
+		break
+	}
+	return "unknown"
+}
+
+// ArrowDirection : The direction an arrow tile turns
+// the blocks to, or DirNone if the tile is not an arrow
+func ArrowDirection(tid int32) Direction {
+
+	switch tid {
+
+	case TileArrowLeft:
+		return DirLeft
+
+	case TileArrowRight:
+		return DirRight
+
+	case TileArrowUp:
+		return DirUp
+
+	case TileArrowDown:
+		return DirDown
+
+	default:
+		break
+	}
+	return DirNone
+}
+
+// FenceSide : The side of the tile a fence tile cannot
+// be entered from, or DirNone if the tile is not a fence
+func FenceSide(tid int32) Direction {
+
+	if tid < TileFenceLeft || tid > TileFenceDown {
+
+		return DirNone
+	}
+	return Direction(tid-TileFenceLeft) + DirLeft
+}
+
+// Point : A 2-component vector, integer components.
+// Same as core.Point, but we do not want to depend
+// on core here
+type Point struct {
+	X, Y int32
+}
+
+// NewPoint : Constructor for point
+func NewPoint(x, y int32) Point {
+
+	return Point{X: x, Y: y}
+}
+
+// Direction : A direction the blocks can be moved to
+type Direction int32
+
+// Directions
+const (
+	DirNone  Direction = 0
+	DirLeft  Direction = 1
+	DirRight Direction = 2
+	DirUp    Direction = 3
+	DirDown  Direction = 4
+)
+
+// Directions : All the directions a player can choose,
+// in the order the tools try them
+var Directions = []Direction{DirLeft, DirRight, DirUp, DirDown}
+
+// Delta : The change in coordinates one step to
+// the direction causes
+func (dir Direction) Delta() (int32, int32) {
+
+	switch dir {
+
+	case DirLeft:
+		return -1, 0
+
+	case DirRight:
+		return 1, 0
+
+	case DirUp:
+		return 0, -1
+
+	case DirDown:
+		return 0, 1
+
+	default:
+		break
+	}
+	return 0, 0
+}
+
+// FromDelta : The direction of a unit vector, or DirNone
+// if it is not one
+func FromDelta(dx, dy int32) Direction {
+
+	for _, dir := range Directions {
+
+		if x, y := dir.Delta(); x == dx && y == dy {
+
+			return dir
+		}
+	}
+	return DirNone
+}
+
+// String : Name of the direction
+func (dir Direction) String() string {
+
+	names := []string{"none", "left", "right", "up", "down"}
+	if dir < 0 || int(dir) >= len(names) {
+
+		return "none"
+	}
+	return names[dir]
+}
+
+// Variant : How a block reacts to the direction
+// the player chooses
+type Variant int32
+
+// Variants
+const (
+	VariantNormal        Variant = 0
+	VariantMirror        Variant = 1 // Moves to the opposite direction
+	VariantPerpendicular Variant = 2 // Turned 90 degrees clockwise
+)
+
+// ParseVariant : Get a variant by its name
+func ParseVariant(name string) (Variant, error) {
+
+	switch name {
+
+	case "", "normal":
+		return VariantNormal, nil
+
+	case "mirror":
+		return VariantMirror, nil
+
+	case "perpendicular":
+		return VariantPerpendicular, nil
+
+	default:
+		break
+	}
+	return VariantNormal, fmt.Errorf("unknown block variant \"%s\"", name)
+}
+
+// Turn : The direction a block of the variant moves to
+// when the player chooses the given one
+func (v Variant) Turn(dir Direction) Direction {
+
+	switch v {
+
+	case VariantMirror:
+		return [...]Direction{DirNone, DirRight, DirLeft, DirDown, DirUp}[dir]
+
+	case VariantPerpendicular:
+		return [...]Direction{DirNone, DirUp, DirDown, DirRight, DirLeft}[dir]
+
+	default:
+		break
+	}
+	return dir
+}
+
+// Block : A single block on the board. Blocks with
+// ID 0 are neutral, the rest need to find a hole
+// with the same ID
+type Block struct {
+	Pos     Point
+	ID      int32
+	Exist   bool
+	Cells   []Point // Every cell of a multi-cell block, Pos first. Nil otherwise
+	Variant Variant
+	Bomb    bool
+}
+
+// Footprint : The tiles the block covers
+func (bl *Block) Footprint() []Point {
+
+	if len(bl.Cells) == 0 {
+
+		return []Point{bl.Pos}
+	}
+	return bl.Cells
+}
+
+// Covers : Tells if the block covers the given tile
+func (bl *Block) Covers(p Point) bool {
+
+	for _, c := range bl.Footprint() {
+
+		if c == p {
+
+			return true
+		}
+	}
+	return false
+}
+
+// Board : An immutable state of a stage
+type Board struct {
+	width     int32
+	height    int32
+	tiles     []int32
+	blocks    []Block
+	exits     []int32 // The other end of each teleporter, -1 otherwise
+	channels  []int32 // For the plates and the gates, nil means all zero
+	hits      []int32 // Hits the cracked walls still take, nil if none
+	blasted   []bool  // The walls the bombs have destroyed, nil if none
+	capacity  []int32 // Blocks the holes still take, 0 is no limit. Nil if none
+	topology  Topology
+	groupMode bool  // Only the blocks of the active color move
+	active    int32 // The ID of those blocks
+	plugging  bool  // Neutral blocks fill the holes they enter
+	ruleset   Ruleset
+}
+
+// NewBoard : Construct a board from a tile layer. Block
+// tiles are taken out of the layer and turned to blocks
+func NewBoard(width, height int32, layer []int32) (*Board, error) {
+
+	if width <= 0 || height <= 0 {
+
+		return nil, fmt.Errorf("invalid board size %dx%d", width, height)
+	}
+	if int32(len(layer)) != width*height {
+
+		return nil, fmt.Errorf("layer has %d tiles, expected %d",
+			len(layer), width*height)
+	}
+
+	b := new(Board)
+
+	b.width = width
+	b.height = height
+	b.tiles = make([]int32, len(layer))
+	b.blocks = make([]Block, 0)
+
+	var x, y int32
+	for i, v := range layer {
+
+		x = int32(i) % width
+		y = int32(i) / width
+
+		if v >= TileNeutralBlock && v <= TileBlockLast {
+
+			b.blocks = append(b.blocks,
+				Block{Pos: NewPoint(x, y), ID: v - TileNeutralBlock, Exist: true})
+			continue
+		}
+		if v == TileBomb {
+
+			b.blocks = append(b.blocks,
+				Block{Pos: NewPoint(x, y), ID: 0, Exist: true, Bomb: true})
+			continue
+		}
+		b.tiles[i] = v
+	}
+	b.findTeleporterExits()
+	b.updateGates(nil)
+
+	hits := make([]int32, len(b.tiles))
+	for i := range hits {
+
+		hits[i] = 1
+	}
+	b.setWallHits(hits)
+
+	return b, nil
+}
+
+// WithWallHits : Returns a copy of the board where the cracked
+// walls take the given number of hits, one for each tile
+func (b *Board) WithWallHits(hits []int32) (*Board, error) {
+
+	if len(hits) != len(b.tiles) {
+
+		return nil, fmt.Errorf("got %d hit counts, expected %d",
+			len(hits), len(b.tiles))
+	}
+
+	out := b.clone()
+	out.setWallHits(hits)
+
+	return out, nil
+}
+
+func (b *Board) setWallHits(hits []int32) {
+
+	b.hits = nil
+	for i, t := range b.tiles {
+
+		if t != TileCrackedWall {
+			continue
+		}
+		if b.hits == nil {
+
+			b.hits = make([]int32, len(b.tiles))
+		}
+		b.hits[i] = hits[i]
+		if b.hits[i] < 1 {
+
+			b.hits[i] = 1
+		}
+	}
+}
+
+// WithHoleCapacity : Returns a copy of the board where the holes
+// take the given number of blocks, one for each tile, before
+// they turn to floor. Zero means no limit
+func (b *Board) WithHoleCapacity(capacity []int32) (*Board, error) {
+
+	if len(capacity) != len(b.tiles) {
+
+		return nil, fmt.Errorf("got %d capacities, expected %d",
+			len(capacity), len(b.tiles))
+	}
+
+	out := b.clone()
+	out.capacity = nil
+	for i, t := range out.tiles {
+
+		if t < TileHoleFirst || t > TileHoleLast || capacity[i] <= 0 {
+			continue
+		}
+		if out.capacity == nil {
+
+			out.capacity = make([]int32, len(out.tiles))
+		}
+		out.capacity[i] = capacity[i]
+	}
+
+	return out, nil
+}
+
+// HoleCapacity : How many more blocks a hole takes, 0 if
+// there is no limit or the tile is not a hole
+func (b *Board) HoleCapacity(x, y int32) int32 {
+
+	if b.capacity == nil {
+
+		return 0
+	}
+	return b.capacity[b.index(x, y)]
+}
+
+// Capacities : Returns a copy of the hole capacities,
+// one for each tile
+func (b *Board) Capacities() []int32 {
+
+	out := make([]int32, len(b.tiles))
+	if b.capacity != nil {
+
+		copy(out, b.capacity)
+	}
+	return out
+}
+
+// HolesLeft : How many blocks the holes with a
+// capacity still need
+func (b *Board) HolesLeft() int32 {
+
+	count := int32(0)
+	for _, c := range b.capacity {
+
+		count += c
+	}
+	return count
+}
+
+// HitsLeft : How many more hits a cracked wall takes
+// before it breaks, 0 for the other tiles
+func (b *Board) HitsLeft(x, y int32) int32 {
+
+	if b.hits == nil {
+
+		return 0
+	}
+	return b.hits[b.index(x, y)]
+}
+
+// WithChannels : Returns a copy of the board where the plates
+// and the gates use the given channels, one for each tile
+func (b *Board) WithChannels(channels []int32) (*Board, error) {
+
+	if len(channels) != len(b.tiles) {
+
+		return nil, fmt.Errorf("got %d channels, expected %d",
+			len(channels), len(b.tiles))
+	}
+
+	out := b.clone()
+
+	out.channels = make([]int32, len(channels))
+	copy(out.channels, channels)
+
+	out.updateGates(nil)
+
+	return out, nil
+}
+
+// Channel : The channel of a plate or a gate
+func (b *Board) Channel(x, y int32) int32 {
+
+	if b.channels == nil {
+
+		return 0
+	}
+	return b.channels[b.index(x, y)]
+}
+
+// WithVariants : Returns a copy of the board where the
+// blocks get the variants of the tiles they are on
+func (b *Board) WithVariants(variants []Variant) (*Board, error) {
+
+	if len(variants) != len(b.tiles) {
+
+		return nil, fmt.Errorf("got %d variants, expected %d",
+			len(variants), len(b.tiles))
+	}
+
+	out := b.clone()
+	for i := range out.blocks {
+
+		for _, c := range out.blocks[i].Footprint() {
+
+			if v := variants[b.index(c.X, c.Y)]; v != VariantNormal {
+
+				out.blocks[i].Variant = v
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// WithPieces : Returns a copy of the board where the blocks
+// with the same positive piece number, one for each tile,
+// are joined to a single multi-cell block. The blocks of a
+// piece must have the same ID and be connected
+func (b *Board) WithPieces(pieces []int32) (*Board, error) {
+
+	if len(pieces) != len(b.tiles) {
+
+		return nil, fmt.Errorf("got %d piece numbers, expected %d",
+			len(pieces), len(b.tiles))
+	}
+
+	// The blocks are in reading order, so the first
+	// block of a piece is its top-left one
+	first := make(map[int32]int32)
+	out := b.clone()
+	out.blocks = make([]Block, 0, len(b.blocks))
+
+	var n int32
+	var bl *Block
+	for _, v := range b.blocks {
+
+		n = pieces[b.index(v.Pos.X, v.Pos.Y)]
+		if n <= 0 {
+
+			out.blocks = append(out.blocks, v)
+			continue
+		}
+
+		k, ok := first[n]
+		if !ok {
+
+			first[n] = int32(len(out.blocks))
+			v.Cells = []Point{v.Pos}
+			out.blocks = append(out.blocks, v)
+			continue
+		}
+
+		bl = &out.blocks[k]
+		if bl.ID != v.ID {
+
+			return nil, fmt.Errorf("piece %d has blocks of different colors", n)
+		}
+		bl.Cells = append(bl.Cells, v.Pos)
+	}
+
+	for n, k := range first {
+
+		if !isConnected(out.blocks[k].Cells) {
+
+			return nil, fmt.Errorf("the blocks of piece %d are not connected", n)
+		}
+	}
+	out.updateGates(nil)
+
+	return out, nil
+}
+
+// Tells if the cells form a single piece, without
+// going through the edges of the board
+func isConnected(cells []Point) bool {
+
+	reached := make([]bool, len(cells))
+	reached[0] = true
+
+	queue := []int{0}
+	var p Point
+	for len(queue) > 0 {
+
+		p = cells[queue[0]]
+		queue = queue[1:]
+
+		for j, c := range cells {
+
+			if reached[j] {
+				continue
+			}
+			if (c.X-p.X)*(c.X-p.X)+(c.Y-p.Y)*(c.Y-p.Y) == 1 {
+
+				reached[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	for _, r := range reached {
+
+		if !r {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Board) isBlockAt(i int32) bool {
+
+	p := NewPoint(i%b.width, i/b.width)
+	for k := range b.blocks {
+
+		if b.blocks[k].Exist && b.blocks[k].Covers(p) {
+
+			return true
+		}
+	}
+	return false
+}
+
+// Opens and closes the gates depending on the blocks that
+// rest on the plates. A gate never closes on a block. The
+// moving blocks, if given, do not press the plates
+func (b *Board) updateGates(moving []bool) []TileChange {
+
+	var changes []TileChange
+
+	pressed := make(map[int32]bool)
+	for k := range b.blocks {
+
+		if !b.blocks[k].Exist || (moving != nil && moving[k]) {
+			continue
+		}
+
+		for _, c := range b.blocks[k].Footprint() {
+
+			if b.tiles[b.index(c.X, c.Y)] == TilePlate {
+
+				pressed[b.Channel(c.X, c.Y)] = true
+			}
+		}
+	}
+
+	var state int32
+	var p Point
+	for j, t := range b.tiles {
+
+		if t != TileGateClosed && t != TileGateOpen {
+			continue
+		}
+		p = NewPoint(int32(j)%b.width, int32(j)/b.width)
+
+		state = TileGateClosed
+		if pressed[b.Channel(p.X, p.Y)] {
+
+			state = TileGateOpen
+		}
+
+		if state == t ||
+			(state == TileGateClosed && b.isBlockAt(int32(j))) {
+			continue
+		}
+
+		b.tiles[j] = state
+		changes = append(changes, TileChange{Pos: p, Tile: state})
+	}
+
+	return changes
+}
+
+// A teleporter without exactly one partner does nothing
+func (b *Board) findTeleporterExits() {
+
+	b.exits = make([]int32, len(b.tiles))
+
+	pairs := make(map[int32][]int32)
+	for i, t := range b.tiles {
+
+		b.exits[i] = -1
+		if IsTeleporter(t) {
+
+			pairs[t] = append(pairs[t], int32(i))
+		}
+	}
+
+	for _, p := range pairs {
+
+		if len(p) != 2 {
+			continue
+		}
+		b.exits[p[0]] = p[1]
+		b.exits[p[1]] = p[0]
+	}
+}
+
+func (b *Board) clone() *Board {
+
+	out := new(Board)
+
+	out.width = b.width
+	out.height = b.height
+
+	out.tiles = make([]int32, len(b.tiles))
+	copy(out.tiles, b.tiles)
+
+	out.blocks = make([]Block, len(b.blocks))
+	copy(out.blocks, b.blocks)
+
+	if b.hits != nil {
+
+		out.hits = make([]int32, len(b.hits))
+		copy(out.hits, b.hits)
+	}
+	if b.capacity != nil {
+
+		out.capacity = make([]int32, len(b.capacity))
+		copy(out.capacity, b.capacity)
+	}
+	if b.blasted != nil {
+
+		out.blasted = make([]bool, len(b.blasted))
+		copy(out.blasted, b.blasted)
+	}
+
+	// These never change
+	out.exits = b.exits
+	out.channels = b.channels
+	out.topology = b.topology
+	out.groupMode = b.groupMode
+	out.active = b.active
+	out.plugging = b.plugging
+	out.ruleset = b.ruleset
+
+	return out
+}
+
+// Width : Getter for width
+func (b *Board) Width() int32 {
+
+	return b.width
+}
+
+// Height : Getter for height
+func (b *Board) Height() int32 {
+
+	return b.height
+}
+
+// Tile : Get the static tile in the given position. Out of
+// bounds positions are wrapped around
+func (b *Board) Tile(x, y int32) int32 {
+
+	return b.tiles[b.index(x, y)]
+}
+
+// Tiles : Returns a copy of the static tile layer
+func (b *Board) Tiles() []int32 {
+
+	out := make([]int32, len(b.tiles))
+	copy(out, b.tiles)
+
+	return out
+}
+
+// Blocks : Returns a copy of the blocks. The indices
+// stay the same between moves, removed blocks have
+// Exist set to false
+func (b *Board) Blocks() []Block {
+
+	out := make([]Block, len(b.blocks))
+	copy(out, b.blocks)
+
+	for i := range out {
+
+		if out[i].Cells != nil {
+
+			out[i].Cells = append([]Point(nil), out[i].Cells...)
+		}
+	}
+
+	return out
+}
+
+// BlockCount : Number of colored blocks still on the board
+func (b *Board) BlockCount() int32 {
+
+	count := int32(0)
+	for _, bl := range b.blocks {
+
+		if bl.Exist && bl.ID > 0 {
+
+			count++
+		}
+	}
+	return count
+}
+
+// Cleared : Tells if all the colored blocks have
+// found their holes, and the holes with a capacity
+// have been filled
+func (b *Board) Cleared() bool {
+
+	return b.BlockCount() <= 0 && b.HolesLeft() <= 0
+}
+
+// Key : Returns a string that identifies the state of
+// the board. Blocks with the same ID are interchangeable,
+// so two boards that differ only in which one of them is
+// where get the same key
+func (b *Board) Key() string {
+
+	cells := make([]string, 0, len(b.blocks))
+	var pos []string
+	for k := range b.blocks {
+
+		if !b.blocks[k].Exist {
+			continue
+		}
+
+		pos = pos[:0]
+		for _, c := range b.blocks[k].Footprint() {
+
+			pos = append(pos, strconv.Itoa(int(b.index(c.X, c.Y))))
+		}
+		sort.Strings(pos)
+
+		// Blocks of different variants are not interchangeable
+		id := strconv.Itoa(int(b.blocks[k].ID))
+		if b.blocks[k].Variant != VariantNormal {
+
+			id += "v" + strconv.Itoa(int(b.blocks[k].Variant))
+		}
+		if b.blocks[k].Bomb {
+
+			id += "b"
+		}
+		cells = append(cells, strings.Join(pos, "+")+":"+id)
+	}
+	sort.Strings(cells)
+
+	// The tiles that can change
+	for i, t := range b.tiles {
+
+		if t == TileGateOpen {
+
+			cells = append(cells, "g"+strconv.Itoa(i))
+
+		} else if t == TilePlugged {
+
+			cells = append(cells, "p"+strconv.Itoa(i))
+
+		} else if b.blasted != nil && b.blasted[i] {
+
+			cells = append(cells, "x"+strconv.Itoa(i))
+
+		} else if t == TileCrackedWall {
+
+			cells = append(cells, "c"+strconv.Itoa(i)+":"+strconv.Itoa(int(b.hits[i])))
+
+		} else if b.capacity != nil && b.capacity[i] > 0 {
+
+			cells = append(cells, "n"+strconv.Itoa(i)+":"+strconv.Itoa(int(b.capacity[i])))
+		}
+	}
+
+	return strings.Join(cells, ",")
+}
+
+func (b *Board) index(x, y int32) int32 {
+
+	x = negMod(x, b.width)
+	y = negMod(y, b.height)
+
+	return y*b.width + x
+}
+
+// Same as core.NegMod
+func negMod(m, n int32) int32 {
+
+	return (m%n + n) % n
+}
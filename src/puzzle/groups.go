@@ -0,0 +1,127 @@
+package puzzle
+
+import "sort"
+
+// WithGroupMode : Returns a copy of the board where only the
+// blocks of the active color move. The first color becomes
+// the active one
+func (b *Board) WithGroupMode(on bool) *Board {
+
+	out := b.clone()
+	out.groupMode = on
+	out.active = 0
+
+	if groups := out.Groups(); on && len(groups) > 0 {
+
+		out.active = groups[0]
+	}
+	return out
+}
+
+// GroupMode : Tells if only the blocks of the
+// active color move
+func (b *Board) GroupMode() bool {
+
+	return b.groupMode
+}
+
+// Active : The ID of the blocks that move in the group mode
+func (b *Board) Active() int32 {
+
+	return b.active
+}
+
+// Groups : The IDs of the colored blocks on the board,
+// sorted. The neutral blocks have no color to choose, so
+// they never move in the group mode
+func (b *Board) Groups() []int32 {
+
+	found := make(map[int32]bool)
+	out := make([]int32, 0)
+	for _, bl := range b.blocks {
+
+		if bl.Exist && bl.ID != 0 && !found[bl.ID] {
+
+			found[bl.ID] = true
+			out = append(out, bl.ID)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+
+	return out
+}
+
+// WithActive : Returns a copy of the board where the
+// blocks with the given ID are the active ones
+func (b *Board) WithActive(id int32) *Board {
+
+	out := b.clone()
+	out.active = id
+
+	return out
+}
+
+// Cycle : Returns a copy of the board where the next
+// color is the active one. Choosing the color is not a
+// move, so it is not a part of the Key either
+func (b *Board) Cycle() *Board {
+
+	groups := b.Groups()
+	if len(groups) == 0 {
+
+		return b
+	}
+
+	next := groups[0]
+	for _, g := range groups {
+
+		if g > b.active {
+
+			next = g
+			break
+		}
+	}
+	return b.WithActive(next)
+}
+
+// If the blocks of the active color are gone, the
+// next color becomes the active one
+func (b *Board) fixActive() {
+
+	if !b.groupMode {
+
+		return
+	}
+
+	groups := b.Groups()
+	for _, g := range groups {
+
+		if g == b.active {
+
+			return
+		}
+	}
+
+	for _, g := range groups {
+
+		if g > b.active {
+
+			b.active = g
+			return
+		}
+	}
+	if len(groups) > 0 {
+
+		b.active = groups[0]
+	}
+}
+
+// The choices a player has before each move
+func (b *Board) moveGroups() []int32 {
+
+	if !b.groupMode {
+
+		return []int32{b.active}
+	}
+	return b.Groups()
+}
@@ -0,0 +1,94 @@
+package puzzle
+
+import "testing"
+
+func withGroups(b *Board) (*Board, error) {
+
+	return b.WithGroupMode(true), nil
+}
+
+var groupCases = []applyCase{
+	{
+		name:  "only the active color moves",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 0, 1,
+			1, 11, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withGroups,
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 0, 10, 1,
+			1, 11, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "neutral blocks never move",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 0, 1,
+			1, 9, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withGroups,
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 0, 10, 1,
+			1, 9, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "the next color becomes active when the last block clears",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 2, 1,
+			1, 11, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withGroups,
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		check: func(t *testing.T, res *Result) {
+
+			if res.Board.Active() != 2 {
+
+				t.Errorf("color %d is active, expected 2", res.Board.Active())
+			}
+		},
+	},
+}
+
+func TestApplyGroups(t *testing.T) {
+
+	runApplyCases(t, groupCases)
+}
+
+func TestCycle(t *testing.T) {
+
+	b, err := NewBoard(4, 1, []int32{9, 10, 0, 12})
+	if err != nil {
+
+		t.Fatal(err)
+	}
+	b = b.WithGroupMode(true)
+
+	// The neutral block has no color to choose
+	for _, want := range []int32{1, 3, 1} {
+
+		if b.Active() != want {
+
+			t.Errorf("color %d is active, expected %d", b.Active(), want)
+		}
+		b = b.Cycle()
+	}
+}
@@ -0,0 +1,882 @@
+package puzzle
+
+import "strconv"
+
+// Outcome : What happened when a move was applied. With
+// OutcomeLoop, the steps end once the loop is found and
+// the board is left with the blocks still moving, so the
+// move should be treated as a dead end
+type Outcome int32
+
+// Outcomes
+const (
+	OutcomeNone    Outcome = 0 // Nothing could move
+	OutcomeMoved   Outcome = 1
+	OutcomeCleared Outcome = 2
+	OutcomeFailed  Outcome = 3
+	OutcomeLoop    Outcome = 4 // The blocks would slide forever
+)
+
+// Event : Something that happened to a block
+// at the end of a step
+type Event int32
+
+// Events
+const (
+	EventNone     Event = 0
+	EventStopped  Event = 1 // Hit something and stopped
+	EventCleared  Event = 2 // Dropped to a hole of its own color
+	EventFailed   Event = 3 // Dropped to a wrong hole
+	EventExploded Event = 4 // A bomb stopped and destroyed the walls around it
+	EventPlugged  Event = 5 // A neutral block sunk to a hole and filled it
+)
+
+// Move : A single block moving a single tile
+type Move struct {
+	Block      int32
+	From       Point
+	To         Point
+	Dir        Point
+	Wrapped    bool // Went through the edge of the board
+	Teleported bool // Came out of a teleporter, To is the exit
+	Event      Event
+	Painted    bool   // Ran over a paint tile of another color
+	ID         int32  // The ID of the block after the move
+	Parts      []Move // Each cell of a multi-cell block, in the order of Cells
+}
+
+// TileChange : A tile that changed at the end of a step
+type TileChange struct {
+	Pos  Point
+	Tile int32
+}
+
+// Step : The moves all the moving blocks make at
+// the same time
+type Step struct {
+	Moves []Move
+	Tiles []TileChange
+}
+
+// Result : The result of applying a move
+type Result struct {
+	Board        *Board
+	Outcome      Outcome
+	Steps        []Step
+	FailurePoint Point
+}
+
+// Used while a move is being resolved
+type resolver struct {
+	board    *Board
+	dir      Direction // The direction the player chose
+	dirs     []Point   // Arrows may turn the blocks
+	moving   []bool
+	occupied []bool
+	impacts  []int32 // The cracked walls hit during the step
+}
+
+// Tells if a block can move to the given direction. A
+// multi-cell block can move only if all of its cells can,
+// and it never blocks itself
+func (r *resolver) canEnter(i int32, d Point) bool {
+
+	bl := &r.board.blocks[i]
+
+	var k int32
+	var t int32
+	for _, c := range bl.Footprint() {
+
+		p, ok := r.board.Neighbour(c, d)
+		if !ok {
+
+			return false
+		}
+		k = r.board.index(p.X, p.Y)
+		t = r.board.tiles[k]
+
+		if IsSolidTile(t) || (r.occupied[k] && !bl.Covers(p)) {
+
+			return false
+		}
+
+		// Entering from the fenced side means moving
+		// to the opposite direction
+		sx, sy := FenceSide(t).Delta()
+		if (sx != 0 || sy != 0) && sx == -d.X && sy == -d.Y {
+
+			return false
+		}
+	}
+	return true
+}
+
+func (r *resolver) targets(i int32) []Point {
+
+	cells := r.board.blocks[i].Footprint()
+
+	out := make([]Point, len(cells))
+	for k, c := range cells {
+
+		out[k], _ = r.board.Neighbour(c, r.dirs[i])
+	}
+	return out
+}
+
+func overlaps(a, b []Point) bool {
+
+	for _, p := range a {
+
+		for _, q := range b {
+
+			if p == q {
+
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Blocks moving to different directions may run into
+// each other. Of two blocks heading to the same tile,
+// the one with the smaller index gets there
+func (r *resolver) collides(i int32) bool {
+
+	cells := r.board.blocks[i].Footprint()
+	t := r.targets(i)
+
+	for j := range r.board.blocks {
+
+		if int32(j) == i || !r.moving[j] {
+			continue
+		}
+
+		if (int32(j) < i && overlaps(r.targets(int32(j)), t)) ||
+			(overlaps(r.board.blocks[j].Footprint(), t) &&
+				overlaps(r.targets(int32(j)), cells)) {
+
+			return true
+		}
+	}
+	return false
+}
+
+// The blocks that have just started may be heading to the
+// same tile or to each other's tiles. Keeps the ones that
+// would collide where they are before the first step, with
+// the same rules stop uses during the move
+func (r *resolver) cancelCollisions() {
+
+	loop := true
+	for loop {
+
+		loop = false
+		for i := range r.board.blocks {
+
+			if r.moving[i] && !r.canEnter(int32(i), r.dirs[i]) {
+
+				r.moving[i] = false
+				r.setOccupied(int32(i), true)
+				loop = true
+			}
+		}
+		if loop {
+			continue
+		}
+
+		for i := range r.board.blocks {
+
+			if r.moving[i] && r.collides(int32(i)) {
+
+				r.moving[i] = false
+				r.setOccupied(int32(i), true)
+				loop = true
+				break
+			}
+		}
+	}
+}
+
+func (r *resolver) setOccupied(i int32, state bool) {
+
+	for _, c := range r.board.blocks[i].Footprint() {
+
+		r.occupied[r.board.index(c.X, c.Y)] = state
+	}
+}
+
+func (r *resolver) anyMoving() bool {
+
+	for _, m := range r.moving {
+
+		if m {
+			return true
+		}
+	}
+	return false
+}
+
+// Identifies the positions and the directions of the
+// moving blocks, needed to find out if they are
+// going around in circles
+func (r *resolver) movingKey() string {
+
+	buf := make([]byte, 0, 64)
+	for i, bl := range r.board.blocks {
+
+		if !r.moving[i] {
+			continue
+		}
+		buf = strconv.AppendInt(buf, int64(i), 10)
+		buf = append(buf, ':')
+		for _, c := range bl.Footprint() {
+
+			buf = strconv.AppendInt(buf, int64(r.board.index(c.X, c.Y)), 10)
+			buf = append(buf, ':')
+		}
+		buf = strconv.AppendInt(buf, int64(FromDelta(r.dirs[i].X, r.dirs[i].Y)), 10)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(bl.ID), 10)
+		buf = append(buf, ',')
+	}
+	return string(buf)
+}
+
+// All these loops are required to make it
+// possible to move several blocks at the
+// same time "consistently"
+func (r *resolver) start() bool {
+
+	var bl *Block
+	var d Point
+
+	loop := true
+	for loop {
+
+		loop = false
+		for i := range r.board.blocks {
+
+			bl = &r.board.blocks[i]
+			if !bl.Exist || r.moving[i] ||
+				(r.board.groupMode && (bl.ID == 0 || bl.ID != r.board.active)) {
+				continue
+			}
+
+			d.X, d.Y = bl.Variant.Turn(r.dir).Delta()
+			if r.canEnter(int32(i), d) {
+
+				r.moving[i] = true
+				r.dirs[i] = d
+				r.setOccupied(int32(i), false)
+
+				loop = true
+			}
+		}
+	}
+	// The variants may send the blocks head-on
+	r.cancelCollisions()
+
+	return r.anyMoving()
+}
+
+// Tells if the block dropped to a hole. A multi-cell block
+// drops only when all of its cells are over a hole of its
+// color, but any cell over a wrong hole is enough to fail
+func (r *resolver) dropped(bl *Block) Event {
+
+	if bl.ID == 0 && (bl.Bomb || !r.board.plugging) {
+
+		return EventNone
+	}
+
+	all := true
+	var t int32
+	for _, c := range bl.Footprint() {
+
+		t = r.board.tiles[r.board.index(c.X, c.Y)]
+		if t < TileHoleFirst || t > TileHoleLast {
+
+			all = false
+
+		} else if bl.ID != 0 && t-TileHoleFirst != bl.ID-1 {
+
+			return EventFailed
+		}
+	}
+
+	if !all {
+
+		return EventNone
+	}
+	if bl.ID == 0 {
+
+		return EventPlugged
+	}
+	return EventCleared
+}
+
+// Lowers the capacity of the holes a block dropped to.
+// The full ones turn to floor
+func (r *resolver) fillHoles(bl *Block) []TileChange {
+
+	if r.board.capacity == nil {
+
+		return nil
+	}
+
+	var changes []TileChange
+	var k int32
+	for _, c := range bl.Footprint() {
+
+		k = r.board.index(c.X, c.Y)
+		if r.board.capacity[k] <= 0 {
+			continue
+		}
+
+		r.board.capacity[k]--
+		if r.board.capacity[k] == 0 {
+
+			r.board.tiles[k] = TileFloor
+			changes = append(changes, TileChange{Pos: c, Tile: TileFloor})
+		}
+	}
+	return changes
+}
+
+// The first arrow and the first paint tile under the
+// block, and if any of its cells is on a sticky tile
+func (r *resolver) floor(bl *Block) (Direction, int32, bool) {
+
+	arrow := DirNone
+	paint := int32(-1)
+	sticky := false
+
+	var t int32
+	for _, c := range bl.Footprint() {
+
+		t = r.board.tiles[r.board.index(c.X, c.Y)]
+		if t == TileSticky {
+
+			sticky = true
+
+		} else if arrow == DirNone && ArrowDirection(t) != DirNone {
+
+			arrow = ArrowDirection(t)
+
+		} else if paint < 0 {
+
+			paint = PaintID(t)
+		}
+	}
+	return arrow, paint, sticky
+}
+
+// One move for each cell of a multi-cell block. Returns
+// the cells after the move
+func (r *resolver) moveCells(bl *Block, m *Move) []Point {
+
+	cells := make([]Point, len(bl.Cells))
+	m.Parts = make([]Move, len(bl.Cells))
+
+	for k, c := range bl.Cells {
+
+		cells[k], _ = r.board.Neighbour(c, m.Dir)
+		m.Parts[k] = Move{Block: m.Block, From: c, To: cells[k], Dir: m.Dir,
+			Wrapped: cells[k] != NewPoint(c.X+m.Dir.X, c.Y+m.Dir.Y)}
+	}
+	return cells
+}
+
+// Moves every moving block by one tile. Returns the step
+// and true if some block dropped to a wrong hole
+func (r *resolver) advance() (Step, bool) {
+
+	var bl *Block
+	var m Move
+	var d Point
+
+	failed := false
+	step := Step{Moves: make([]Move, 0)}
+
+	for i := range r.board.blocks {
+
+		if !r.moving[i] {
+			continue
+		}
+		bl = &r.board.blocks[i]
+		d = r.dirs[i]
+
+		m = Move{Block: int32(i), From: bl.Pos, Dir: d}
+		m.To, _ = r.board.Neighbour(bl.Pos, d)
+		m.Wrapped = m.To != NewPoint(bl.Pos.X+d.X, bl.Pos.Y+d.Y)
+
+		// The cells are shared with the earlier boards,
+		// so they are replaced instead of modified
+		if len(bl.Cells) > 0 {
+
+			bl.Cells = r.moveCells(bl, &m)
+		}
+		bl.Pos = m.To
+
+		arrow, paint, sticky := r.floor(bl)
+		if paint >= 0 && paint != bl.ID && !bl.Bomb {
+
+			bl.ID = paint
+			m.Painted = true
+		}
+		m.ID = bl.ID
+
+		// Check if hits a hole
+		if ev := r.dropped(bl); ev != EventNone {
+
+			r.moving[i] = false
+			m.Event = ev
+
+			if ev == EventCleared {
+
+				// The hole stays "solid" until everything
+				// has stopped
+				bl.Exist = false
+				r.setOccupied(int32(i), true)
+
+				step.Tiles = append(step.Tiles, r.fillHoles(bl)...)
+
+			} else if ev == EventPlugged {
+
+				bl.Exist = false
+				r.setOccupied(int32(i), true)
+
+				step.Tiles = append(step.Tiles, r.plugHoles(bl)...)
+
+			} else {
+
+				failed = true
+			}
+
+		} else if sticky {
+
+			r.moving[i] = false
+			r.setOccupied(int32(i), true)
+
+			m.Event = EventStopped
+
+		} else if arrow != DirNone {
+
+			r.dirs[i].X, r.dirs[i].Y = arrow.Delta()
+		}
+
+		step.Moves = append(step.Moves, m)
+	}
+	r.teleport(&step)
+
+	return step, failed
+}
+
+// Moves the blocks that arrived on a teleporter to the other
+// end, if there is room. Otherwise they slide over it
+func (r *resolver) teleport(step *Step) {
+
+	var bl *Block
+	var exit int32
+
+	for k, m := range step.Moves {
+
+		// Multi-cell blocks do not fit through
+		if !r.moving[m.Block] || len(m.Parts) > 0 {
+			continue
+		}
+		bl = &r.board.blocks[m.Block]
+
+		exit = r.board.exits[r.board.index(bl.Pos.X, bl.Pos.Y)]
+		if exit < 0 || r.occupied[exit] || r.board.isBlockAt(exit) {
+			continue
+		}
+
+		bl.Pos = NewPoint(exit%r.board.width, exit/r.board.width)
+
+		step.Moves[k].To = bl.Pos
+		step.Moves[k].Teleported = true
+	}
+}
+
+func (r *resolver) halt(step *Step, k int) {
+
+	i := step.Moves[k].Block
+
+	r.moving[i] = false
+	r.setOccupied(i, true)
+
+	step.Moves[k].Event = EventStopped
+}
+
+// Remembers the cracked walls in front of a block that
+// could not continue
+func (r *resolver) impact(i int32) {
+
+	var k int32
+	for _, c := range r.board.blocks[i].Footprint() {
+
+		p, ok := r.board.Neighbour(c, r.dirs[i])
+		if !ok {
+			continue
+		}
+
+		k = r.board.index(p.X, p.Y)
+		if r.board.tiles[k] == TileCrackedWall {
+
+			r.impacts = append(r.impacts, k)
+		}
+	}
+}
+
+// Counts the hits of the step and turns the walls that
+// broke to floor. The blocks that hit them have stopped
+// already, so this cannot affect the step itself
+func (r *resolver) crackWalls() []TileChange {
+
+	var changes []TileChange
+	for _, k := range r.impacts {
+
+		if r.board.tiles[k] != TileCrackedWall {
+			continue
+		}
+
+		r.board.hits[k]--
+		if r.board.hits[k] <= 0 {
+
+			r.board.tiles[k] = TileFloor
+			changes = append(changes, TileChange{
+				Pos:  NewPoint(k%r.board.width, k/r.board.width),
+				Tile: TileFloor})
+		}
+	}
+	r.impacts = r.impacts[:0]
+
+	return changes
+}
+
+// Stop the blocks that cannot continue. Stopping a block
+// may stop the ones behind it, so loop until nothing changes
+func (r *resolver) stop(step *Step) {
+
+	loop := true
+	for loop {
+
+		loop = false
+		for k, m := range step.Moves {
+
+			if !r.moving[m.Block] {
+				continue
+			}
+
+			if !r.canEnter(m.Block, r.dirs[m.Block]) {
+
+				r.impact(m.Block)
+				r.halt(step, k)
+				loop = true
+			}
+		}
+		if loop {
+			continue
+		}
+
+		// Collisions between the moving blocks are checked
+		// only when nothing else stops, one at a time
+		for k, m := range step.Moves {
+
+			if r.moving[m.Block] && r.collides(m.Block) {
+
+				r.halt(step, k)
+				loop = true
+				break
+			}
+		}
+	}
+}
+
+func newResolver(b *Board, dir Direction) *resolver {
+
+	r := new(resolver)
+
+	r.board = b
+	r.dir = dir
+	r.dirs = make([]Point, len(b.blocks))
+	r.moving = make([]bool, len(b.blocks))
+	r.occupied = make([]bool, len(b.tiles))
+
+	for i, bl := range b.blocks {
+
+		if bl.Exist {
+
+			r.setOccupied(int32(i), true)
+		}
+	}
+
+	return r
+}
+
+// Apply : Move all the blocks to the given direction until
+// they stop. Returns the new board, the outcome and every
+// step on the way so that the move can be animated
+func (b *Board) Apply(dir Direction) *Result {
+
+	res := new(Result)
+	res.Board = b
+	res.Outcome = OutcomeNone
+	res.Steps = make([]Step, 0)
+
+	if dir == DirNone {
+
+		return res
+	}
+
+	r := newResolver(b.clone(), dir)
+	if !r.start() {
+
+		return res
+	}
+	res.Board = r.board
+	res.Outcome = OutcomeMoved
+
+	var step Step
+	var failed bool
+	var idle bool
+	var key string
+
+	// If the moving blocks get to the same positions twice
+	// without anything happening in between, they would
+	// be sliding around forever
+	seen := make(map[string]bool)
+
+	for r.anyMoving() {
+
+		step, failed = r.resolveStep(r.board.ruleset == RulesetStep)
+		if failed {
+
+			res.Steps = append(res.Steps, step)
+			res.fail(step)
+			return res
+		}
+
+		// With the step ruleset, the blocks that could
+		// still go on stop after the first tile
+		if r.board.ruleset == RulesetStep {
+
+			r.settle(&step)
+			res.Steps = append(res.Steps, step)
+			break
+		}
+		res.Steps = append(res.Steps, step)
+
+		idle = len(step.Tiles) == 0
+		for _, m := range step.Moves {
+
+			if m.Event != EventNone {
+
+				idle = false
+				break
+			}
+		}
+
+		if !idle {
+
+			if len(seen) > 0 {
+
+				seen = make(map[string]bool)
+			}
+			continue
+		}
+
+		key = r.movingKey()
+		if seen[key] {
+
+			res.FailurePoint = step.Moves[0].To
+			res.Outcome = OutcomeLoop
+			return res
+		}
+		seen[key] = true
+	}
+
+	// The conveyors push the blocks once the move has settled.
+	// The push is a single tile, so the blocks do not hit
+	// anything, they just stop
+	if !r.board.Cleared() && r.startConveyors() {
+
+		step, failed = r.resolveStep(true)
+		res.Steps = append(res.Steps, step)
+
+		if failed {
+
+			res.fail(step)
+			return res
+		}
+		r.settle(&step)
+	}
+
+	if r.board.Cleared() {
+
+		res.Outcome = OutcomeCleared
+
+	} else if r.board.plugging && r.board.Unclearable() {
+
+		res.stuck()
+		return res
+	}
+	r.board.fixActive()
+
+	return res
+}
+
+func (res *Result) fail(step Step) {
+
+	for _, m := range step.Moves {
+
+		if m.Event == EventFailed {
+
+			res.FailurePoint = m.To
+			break
+		}
+	}
+	res.Outcome = OutcomeFailed
+}
+
+// Moves the blocks one tile and stops the ones that
+// cannot continue. Returns the step and true if some
+// block dropped to a wrong hole. If the step is the
+// last one anyway, settle stops the blocks instead and
+// nothing gets hit
+func (r *resolver) resolveStep(last bool) (Step, bool) {
+
+	step, failed := r.advance()
+	if failed {
+
+		return step, true
+	}
+
+	if last {
+
+		return step, false
+	}
+
+	r.stop(&step)
+
+	// Closing gates may stop more blocks, and
+	// those may press plates in turn
+	changes := r.board.updateGates(r.moving)
+	for len(changes) > 0 {
+
+		step.Tiles = append(step.Tiles, changes...)
+		r.stop(&step)
+
+		changes = r.board.updateGates(r.moving)
+	}
+	step.Tiles = append(step.Tiles, r.crackWalls()...)
+	step.Tiles = append(step.Tiles, r.explode(&step)...)
+
+	return step, false
+}
+
+// The bombs that stopped during the step destroy the walls
+// in the 3x3 area around them and disappear
+func (r *resolver) explode(step *Step) []TileChange {
+
+	var changes []TileChange
+	var bl *Block
+	var k int32
+
+	for n, m := range step.Moves {
+
+		bl = &r.board.blocks[m.Block]
+		if !bl.Bomb || !bl.Exist || r.moving[m.Block] ||
+			(m.Event != EventStopped && m.Event != EventNone) {
+			continue
+		}
+
+		for _, p := range r.board.area(bl.Pos) {
+
+			k = r.board.index(p.X, p.Y)
+			if r.board.tiles[k] != TileWall {
+				continue
+			}
+
+			// The walls are not a part of the key otherwise
+			if r.board.blasted == nil {
+
+				r.board.blasted = make([]bool, len(r.board.tiles))
+			}
+			r.board.blasted[k] = true
+
+			r.board.tiles[k] = TileFloor
+			changes = append(changes, TileChange{Pos: p, Tile: TileFloor})
+		}
+
+		r.setOccupied(m.Block, false)
+		bl.Exist = false
+
+		step.Moves[n].Event = EventExploded
+	}
+	return changes
+}
+
+// Starts the blocks that rest on a conveyor to the direction
+// of the belt. Pushing a block may make room for another
+// one, so loop like in start
+func (r *resolver) startConveyors() bool {
+
+	var d Point
+
+	loop := true
+	for loop {
+
+		loop = false
+		for i := range r.board.blocks {
+
+			if !r.board.blocks[i].Exist || r.moving[i] {
+				continue
+			}
+
+			d.X, d.Y = r.belt(&r.board.blocks[i]).Delta()
+			if (d.X != 0 || d.Y != 0) && r.canEnter(int32(i), d) {
+
+				r.moving[i] = true
+				r.dirs[i] = d
+				r.setOccupied(int32(i), false)
+
+				loop = true
+			}
+		}
+	}
+	r.cancelCollisions()
+
+	return r.anyMoving()
+}
+
+// The direction of the first conveyor under the block
+func (r *resolver) belt(bl *Block) Direction {
+
+	for _, c := range bl.Footprint() {
+
+		if dir := ConveyorDirection(r.board.tiles[r.board.index(c.X, c.Y)]); dir != DirNone {
+
+			return dir
+		}
+	}
+	return DirNone
+}
+
+// A conveyor pushes the blocks by one tile only, so
+// the ones still moving stop where they are
+func (r *resolver) settle(step *Step) {
+
+	for _, m := range step.Moves {
+
+		if r.moving[m.Block] {
+
+			r.moving[m.Block] = false
+			r.setOccupied(m.Block, true)
+		}
+	}
+	step.Tiles = append(step.Tiles, r.explode(step)...)
+	step.Tiles = append(step.Tiles, r.board.updateGates(nil)...)
+}
@@ -0,0 +1,1009 @@
+package puzzle
+
+import (
+	"fmt"
+	"testing"
+)
+
+// A single move on a small board. The layers use the
+// tile IDs of the stage files, blocks included
+type applyCase struct {
+	name    string
+	width   int32
+	layer   []int32
+	setup   func(b *Board) (*Board, error) // Optional
+	dir     Direction
+	outcome Outcome
+	want    []int32                         // The layer after the move, nil to skip
+	check   func(t *testing.T, res *Result) // Optional
+}
+
+// The tiles of the board with the blocks put back
+// where they are, like in the stage files
+func layerOf(b *Board) []int32 {
+
+	out := b.Tiles()
+	for _, bl := range b.Blocks() {
+
+		if !bl.Exist {
+			continue
+		}
+
+		tid := TileNeutralBlock + bl.ID
+		if bl.Bomb {
+
+			tid = TileBomb
+		}
+		for _, c := range bl.Footprint() {
+
+			out[c.Y*b.Width()+c.X] = tid
+		}
+	}
+	return out
+}
+
+func formatLayer(layer []int32, width int32) string {
+
+	s := ""
+	for i, v := range layer {
+
+		if int32(i)%width == 0 {
+
+			s += "\n"
+		}
+		s += fmt.Sprintf("%3d", v)
+	}
+	return s
+}
+
+func runApplyCases(t *testing.T, cases []applyCase) {
+
+	for _, tc := range cases {
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			b, err := NewBoard(tc.width, int32(len(tc.layer))/tc.width, tc.layer)
+			if err != nil {
+
+				t.Fatal(err)
+			}
+			if tc.setup != nil {
+
+				b, err = tc.setup(b)
+				if err != nil {
+
+					t.Fatal(err)
+				}
+			}
+
+			res := b.Apply(tc.dir)
+			if res.Outcome != tc.outcome {
+
+				t.Errorf("outcome %d, expected %d", res.Outcome, tc.outcome)
+			}
+
+			if tc.want != nil {
+
+				got := layerOf(res.Board)
+				if formatLayer(got, tc.width) != formatLayer(tc.want, tc.width) {
+
+					t.Errorf("got layer%s\nexpected%s",
+						formatLayer(got, tc.width), formatLayer(tc.want, tc.width))
+				}
+			}
+
+			if tc.check != nil {
+
+				tc.check(t, res)
+			}
+		})
+	}
+}
+
+var basicCases = []applyCase{
+	{
+		name:  "slides until a wall",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 0, 10, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "stops behind another block",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 9, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 10, 9, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "nothing can move",
+		width: 4,
+		layer: []int32{
+			1, 1, 1, 1,
+			1, 0, 10, 1,
+			1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeNone,
+	},
+	{
+		name:  "clears into the right hole",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 2, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeCleared,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 0, 2, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "neutral blocks roll over holes",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 9, 2, 0, 1,
+			1, 0, 0, 10, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 2, 9, 1,
+			1, 0, 0, 10, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "fails into the wrong hole",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 3, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeFailed,
+		check: func(t *testing.T, res *Result) {
+
+			if res.FailurePoint != NewPoint(3, 1) {
+
+				t.Errorf("failure point %v, expected (3, 1)", res.FailurePoint)
+			}
+		},
+	},
+	{
+		name:  "wraps around the edge",
+		width: 4,
+		layer: []int32{
+			1, 0, 1, 1,
+			1, 1, 1, 1,
+			1, 10, 1, 1,
+		},
+		dir:     DirDown,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 10, 1, 1,
+			1, 1, 1, 1,
+			1, 0, 1, 1,
+		},
+	},
+	{
+		name:  "slides forever around a torus",
+		width: 3,
+		layer: []int32{
+			10, 0, 0,
+		},
+		dir:     DirRight,
+		outcome: OutcomeLoop,
+	},
+}
+
+func TestApplyBasics(t *testing.T) {
+
+	runApplyCases(t, basicCases)
+}
+
+var arrowAndFenceCases = []applyCase{
+	{
+		name:  "arrows turn the blocks",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 0, 0, 1,
+			1, 10, 8, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 10, 0, 1,
+			1, 0, 8, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "a block may stop on an arrow",
+		width: 4,
+		layer: []int32{
+			1, 1, 1, 1,
+			1, 10, 8, 1,
+			1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1,
+			1, 0, 10, 1,
+			1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "arrows can send the blocks around forever",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 7, 10, 17, 1,
+			1, 0, 1, 0, 1,
+			1, 8, 0, 6, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeLoop,
+	},
+	{
+		name:  "fences stop the blocks from the fenced side",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 10, 0, 18, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 10, 18, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "fences let the blocks through from the other side",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 18, 0, 10, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirLeft,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 10, 18, 0, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+}
+
+func TestApplyArrowsAndFences(t *testing.T) {
+
+	runApplyCases(t, arrowAndFenceCases)
+}
+
+var stickyCases = []applyCase{
+	{
+		name:  "sticky tiles stop the blocks",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 10, 0, 22, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 0, 10, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "a block behind a stuck one stops, too",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 11, 10, 22, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 11, 10, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+}
+
+func TestApplySticky(t *testing.T) {
+
+	runApplyCases(t, stickyCases)
+}
+
+var teleporterCases = []applyCase{
+	{
+		name:  "blocks come out of the other teleporter",
+		width: 7,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1, 1,
+			1, 10, 23, 1, 0, 0, 1,
+			1, 0, 0, 1, 23, 0, 1,
+			1, 1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1, 1,
+			1, 0, 23, 1, 0, 0, 1,
+			1, 0, 0, 1, 23, 10, 1,
+			1, 1, 1, 1, 1, 1, 1,
+		},
+		check: func(t *testing.T, res *Result) {
+
+			if !res.Steps[0].Moves[0].Teleported {
+
+				t.Error("the first step is not a teleport")
+			}
+		},
+	},
+	{
+		name:  "a teleporter without a pair is floor",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 24, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 24, 10, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+}
+
+func TestApplyTeleporters(t *testing.T) {
+
+	runApplyCases(t, teleporterCases)
+}
+
+var gateCases = []applyCase{
+	{
+		name:  "a block resting on a plate opens the gates",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 9, 27, 1, 1, 1,
+			1, 10, 0, 28, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 9, 1, 1, 1,
+			1, 0, 10, 29, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "gates of another channel stay closed",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 9, 27, 1, 1, 1,
+			1, 10, 0, 28, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		setup: func(b *Board) (*Board, error) {
+
+			channels := make([]int32, 6*4)
+			channels[2*6+3] = 1
+
+			return b.WithChannels(channels)
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 9, 1, 1, 1,
+			1, 0, 10, 28, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "a block that slides over a plate does not open the gates",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 9, 27, 0, 1, 1,
+			1, 10, 28, 0, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 27, 9, 1, 1,
+			1, 10, 28, 0, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+}
+
+func TestApplyGates(t *testing.T) {
+
+	runApplyCases(t, gateCases)
+}
+
+// Joins the blocks at the given tiles to a single piece
+func withPiece(width, height int32, cells ...Point) func(b *Board) (*Board, error) {
+
+	return func(b *Board) (*Board, error) {
+
+		pieces := make([]int32, width*height)
+		for _, c := range cells {
+
+			pieces[c.Y*width+c.X] = 1
+		}
+		return b.WithPieces(pieces)
+	}
+}
+
+var pieceCases = []applyCase{
+	{
+		name:  "a piece stops when any of its cells does",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 0, 1,
+			1, 10, 0, 1, 1,
+			1, 0, 0, 0, 11,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withPiece(5, 5, NewPoint(1, 1), NewPoint(1, 2)),
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 10, 0, 1,
+			1, 0, 10, 1, 1,
+			1, 0, 0, 0, 11,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "a piece drops only when every cell is over a hole",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 2, 2, 1,
+			1, 10, 0, 2, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withPiece(5, 4, NewPoint(1, 1), NewPoint(1, 2)),
+		dir:     DirRight,
+		outcome: OutcomeCleared,
+		check: func(t *testing.T, res *Result) {
+
+			last := res.Steps[len(res.Steps)-1].Moves[0]
+			if len(res.Steps) != 2 || last.Event != EventCleared {
+
+				t.Errorf("the piece dropped after %d steps, event %d",
+					len(res.Steps), last.Event)
+			}
+		},
+	},
+	{
+		name:  "one cell over a wrong hole is enough to fail",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 3, 0, 1,
+			1, 10, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withPiece(5, 4, NewPoint(1, 1), NewPoint(1, 2)),
+		dir:     DirRight,
+		outcome: OutcomeFailed,
+	},
+}
+
+func TestApplyPieces(t *testing.T) {
+
+	runApplyCases(t, pieceCases)
+}
+
+func TestWithPiecesChecks(t *testing.T) {
+
+	layer := []int32{
+		10, 11, 0,
+		0, 0, 10,
+	}
+	b, err := NewBoard(3, 2, layer)
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	if _, err := withPiece(3, 2, NewPoint(0, 0), NewPoint(1, 0))(b); err == nil {
+
+		t.Error("a piece of two colors is accepted")
+	}
+	if _, err := withPiece(3, 2, NewPoint(0, 0), NewPoint(2, 1))(b); err == nil {
+
+		t.Error("a piece that is not connected is accepted")
+	}
+}
+
+var paintCases = []applyCase{
+	{
+		name:  "a painted neutral block drops to a hole",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 9, 31, 2, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeCleared,
+		check: func(t *testing.T, res *Result) {
+
+			m := res.Steps[0].Moves[0]
+			if !m.Painted || m.ID != 1 {
+
+				t.Errorf("painted %v to %d, expected to 1", m.Painted, m.ID)
+			}
+		},
+	},
+	{
+		name:  "a block painted neutral rolls over holes",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 10, 30, 3, 0, 1,
+			1, 0, 0, 0, 11, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 30, 3, 9, 1,
+			1, 0, 0, 0, 11, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "a painted block fails in its old hole",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 32, 2, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeFailed,
+	},
+}
+
+func TestApplyPaint(t *testing.T) {
+
+	runApplyCases(t, paintCases)
+}
+
+// Every cracked wall takes the given number of hits
+func withHits(n int32) func(b *Board) (*Board, error) {
+
+	return func(b *Board) (*Board, error) {
+
+		hits := make([]int32, b.Width()*b.Height())
+		for i := range hits {
+
+			hits[i] = n
+		}
+		return b.WithWallHits(hits)
+	}
+}
+
+var crackedWallCases = []applyCase{
+	{
+		name:  "a cracked wall breaks after the last hit",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 35, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 10, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "a cracked wall counts the hits",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 35, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withHits(2),
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 10, 35, 1,
+			1, 1, 1, 1, 1,
+		},
+		check: func(t *testing.T, res *Result) {
+
+			if n := res.Board.HitsLeft(3, 1); n != 1 {
+
+				t.Errorf("%d hits left, expected 1", n)
+			}
+		},
+	},
+	{
+		name:  "a block that does not move does not hit",
+		width: 4,
+		layer: []int32{
+			1, 1, 1, 1,
+			1, 0, 10, 35,
+			1, 1, 1, 1,
+		},
+		setup:   withTopology("none"),
+		dir:     DirRight,
+		outcome: OutcomeNone,
+	},
+}
+
+func TestApplyCrackedWalls(t *testing.T) {
+
+	runApplyCases(t, crackedWallCases)
+}
+
+// Every hole takes the given number of blocks
+func withCapacity(n int32) func(b *Board) (*Board, error) {
+
+	return func(b *Board) (*Board, error) {
+
+		capacity := make([]int32, b.Width()*b.Height())
+		for i := range capacity {
+
+			capacity[i] = n
+		}
+		return b.WithHoleCapacity(capacity)
+	}
+}
+
+var capacityCases = []applyCase{
+	{
+		name:  "a full hole turns to floor",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 2, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withCapacity(1),
+		dir:     DirRight,
+		outcome: OutcomeCleared,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "the stage is not cleared before the holes are full",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 2, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withCapacity(2),
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		check: func(t *testing.T, res *Result) {
+
+			if n := res.Board.HoleCapacity(3, 1); n != 1 {
+
+				t.Errorf("the hole takes %d more blocks, expected 1", n)
+			}
+		},
+	},
+}
+
+func TestApplyCapacity(t *testing.T) {
+
+	runApplyCases(t, capacityCases)
+}
+
+var conveyorCases = []applyCase{
+	{
+		name:  "conveyors push the resting blocks one tile",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 10, 0, 0, 0, 1,
+			1, 37, 0, 0, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirDown,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 0, 0, 0, 1,
+			1, 37, 10, 0, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "blocks pushed toward each other do not share a tile",
+		width: 5,
+		layer: []int32{
+			1, 10, 1, 11, 1,
+			1, 37, 0, 36, 1,
+		},
+		setup:   withTopology("none"),
+		dir:     DirDown,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 0, 1, 0, 1,
+			1, 37, 10, 11, 1,
+		},
+	},
+	{
+		name:  "blocks pushed against each other stay where they are",
+		width: 4,
+		layer: []int32{
+			1, 10, 11, 1,
+			1, 37, 36, 1,
+		},
+		setup:   withTopology("none"),
+		dir:     DirDown,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 0, 0, 1,
+			1, 10, 11, 1,
+		},
+	},
+	{
+		name:  "a push does not crack the wall next to the block",
+		width: 5,
+		layer: []int32{
+			1, 10, 1, 1, 1,
+			1, 37, 0, 35, 1,
+			1, 1, 1, 1, 1,
+		},
+		dir:     DirDown,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 0, 1, 1, 1,
+			1, 37, 10, 35, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+}
+
+func TestApplyConveyors(t *testing.T) {
+
+	runApplyCases(t, conveyorCases)
+}
+
+// Gives the blocks at the given tiles a variant
+func withVariant(v Variant, cells ...Point) func(b *Board) (*Board, error) {
+
+	return func(b *Board) (*Board, error) {
+
+		variants := make([]Variant, b.Width()*b.Height())
+		for _, c := range cells {
+
+			variants[c.Y*b.Width()+c.X] = v
+		}
+		return b.WithVariants(variants)
+	}
+}
+
+var variantCases = []applyCase{
+	{
+		name:  "mirror blocks move to the opposite direction",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 11, 0, 1,
+			1, 0, 10, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withVariant(VariantMirror, NewPoint(2, 1)),
+		dir:     DirLeft,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 0, 11, 1,
+			1, 10, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "perpendicular blocks turn clockwise",
+		width: 4,
+		layer: []int32{
+			1, 1, 1, 1,
+			1, 11, 0, 1,
+			1, 0, 0, 1,
+			1, 1, 1, 1,
+		},
+		setup:   withVariant(VariantPerpendicular, NewPoint(1, 1)),
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1,
+			1, 0, 0, 1,
+			1, 11, 0, 1,
+			1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "head-on blocks do not pass through each other",
+		width: 3,
+		layer: []int32{
+			10, 0, 11,
+		},
+		setup: func(b *Board) (*Board, error) {
+
+			b, _ = withTopology("none")(b)
+			return withVariant(VariantMirror, NewPoint(b.Width()-1, 0))(b)
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			0, 10, 11,
+		},
+	},
+	{
+		name:  "head-on blocks meet in the middle",
+		width: 4,
+		layer: []int32{
+			10, 0, 0, 11,
+		},
+		setup: func(b *Board) (*Board, error) {
+
+			b, _ = withTopology("none")(b)
+			return withVariant(VariantMirror, NewPoint(b.Width()-1, 0))(b)
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			0, 10, 11, 0,
+		},
+	},
+}
+
+func TestApplyVariants(t *testing.T) {
+
+	runApplyCases(t, variantCases)
+}
+
+var bombCases = []applyCase{
+	{
+		name:  "a bomb destroys the walls around it when it stops",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 40, 0, 0, 1, 1,
+			1, 1, 1, 1, 1, 1,
+			1, 1, 1, 1, 1, 1,
+			1, 10, 0, 0, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 0, 0, 0, 1,
+			1, 0, 0, 0, 0, 1,
+			1, 1, 0, 0, 0, 1,
+			1, 1, 1, 1, 1, 1,
+			1, 0, 0, 0, 10, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		check: func(t *testing.T, res *Result) {
+
+			if res.Board.Blocks()[0].Exist {
+
+				t.Error("the bomb did not explode")
+			}
+		},
+	},
+	{
+		name:  "bombs are not painted",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 40, 31, 0, 0, 1,
+			1, 0, 0, 0, 10, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		check: func(t *testing.T, res *Result) {
+
+			if res.Steps[0].Moves[0].Painted {
+
+				t.Error("the bomb was painted")
+			}
+		},
+	},
+}
+
+func TestApplyBombs(t *testing.T) {
+
+	runApplyCases(t, bombCases)
+}
+
+func TestKeyCoversBlastedWalls(t *testing.T) {
+
+	layer := []int32{
+		1, 1, 1, 1, 1, 1, 1,
+		1, 1, 0, 40, 0, 1, 1,
+		1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 10, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1,
+	}
+	b, err := NewBoard(7, 5, layer)
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	// The bomb is gone either way, and the
+	// colored block cannot move at all
+	left := b.Apply(DirLeft).Board
+	right := b.Apply(DirRight).Board
+	if left.Key() == right.Key() {
+
+		t.Errorf("boards with different walls share the key %s", left.Key())
+	}
+}
@@ -0,0 +1,132 @@
+package puzzle
+
+import "math"
+
+// WithPlugging : Returns a copy of the board where the
+// neutral blocks fill any hole they enter, turning it
+// to floor
+func (b *Board) WithPlugging(on bool) *Board {
+
+	out := b.clone()
+	out.plugging = on
+
+	return out
+}
+
+// Plugging : Tells if the neutral blocks fill the holes
+func (b *Board) Plugging() bool {
+
+	return b.plugging
+}
+
+// Unclearable : Tells if some colored block has no room
+// left in the holes of its color. Paint tiles may give the
+// block another color, so the holes of those colors count,
+// too
+func (b *Board) Unclearable() bool {
+
+	_, ok := b.hopeless()
+	return ok
+}
+
+// The position of the first block that can no longer be
+// cleared, and false if there is no such block. The holes
+// without a capacity take any number of blocks
+func (b *Board) hopeless() (Point, bool) {
+
+	const unlimited int32 = math.MaxInt32
+
+	room := make(map[int32]int32)
+	paints := make([]int32, 0)
+	var id int32
+	for i, t := range b.tiles {
+
+		if t >= TileHoleFirst && t <= TileHoleLast {
+
+			id = t - TileHoleFirst + 1
+			if b.capacity == nil || b.capacity[i] <= 0 {
+
+				room[id] = unlimited
+
+			} else if room[id] < unlimited {
+
+				room[id] += b.capacity[i]
+			}
+
+		} else if id = PaintID(t); id > 0 {
+
+			paints = append(paints, id)
+		}
+	}
+
+	// Without paint, the blocks of a color share the
+	// holes of that color
+	need := make(map[int32]int32)
+	var cells int32
+	var ok bool
+	for _, bl := range b.blocks {
+
+		if !bl.Exist || bl.ID == 0 {
+			continue
+		}
+
+		cells = int32(len(bl.Footprint()))
+		need[bl.ID] += cells
+
+		ok = room[bl.ID] >= cells
+		if len(paints) == 0 {
+
+			ok = room[bl.ID] >= need[bl.ID]
+		}
+		for _, id := range paints {
+
+			ok = ok || room[id] >= cells
+		}
+
+		if !ok {
+
+			return bl.Pos, true
+		}
+	}
+	return Point{}, false
+}
+
+// Turns the holes under a neutral block to plugged holes.
+// They take no more blocks, either
+func (r *resolver) plugHoles(bl *Block) []TileChange {
+
+	var changes []TileChange
+	var k int32
+	for _, c := range bl.Footprint() {
+
+		k = r.board.index(c.X, c.Y)
+		if r.board.capacity != nil {
+
+			r.board.capacity[k] = 0
+		}
+
+		r.board.tiles[k] = TilePlugged
+		changes = append(changes, TileChange{Pos: c, Tile: TilePlugged})
+	}
+	return changes
+}
+
+// The last hole that was plugged is the one to blame
+// if the colored blocks can no longer be cleared. If
+// nothing was plugged, a block was painted a color
+// without holes, so blame that block
+func (res *Result) stuck() {
+
+	res.FailurePoint, _ = res.Board.hopeless()
+	for _, s := range res.Steps {
+
+		for _, m := range s.Moves {
+
+			if m.Event == EventPlugged {
+
+				res.FailurePoint = m.To
+			}
+		}
+	}
+	res.Outcome = OutcomeFailed
+}
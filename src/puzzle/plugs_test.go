@@ -0,0 +1,126 @@
+package puzzle
+
+import "testing"
+
+func withPlugs(b *Board) (*Board, error) {
+
+	return b.WithPlugging(true), nil
+}
+
+var plugCases = []applyCase{
+	{
+		name:  "a neutral block plugs any hole",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 9, 0, 3, 0, 1,
+			1, 10, 0, 0, 2, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		setup:   withPlugs,
+		dir:     DirRight,
+		outcome: OutcomeCleared,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 0, 41, 0, 1,
+			1, 0, 0, 0, 2, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "neutral blocks roll over holes without the option",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 9, 0, 3, 0, 1,
+			1, 0, 0, 0, 10, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 0, 3, 9, 1,
+			1, 0, 0, 0, 10, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "plugging the last hole of a color fails",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 9, 0, 2, 0, 1,
+			1, 0, 0, 0, 10, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		setup:   withPlugs,
+		dir:     DirRight,
+		outcome: OutcomeFailed,
+		check: func(t *testing.T, res *Result) {
+
+			if res.FailurePoint != NewPoint(3, 1) {
+
+				t.Errorf("failure point %v, expected the hole at (3, 1)",
+					res.FailurePoint)
+			}
+		},
+	},
+	{
+		name:  "a block painted a color without holes fails",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 10, 32, 0, 0, 1,
+			1, 2, 1, 1, 1, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		setup:   withPlugs,
+		dir:     DirRight,
+		outcome: OutcomeFailed,
+		check: func(t *testing.T, res *Result) {
+
+			if res.FailurePoint != NewPoint(4, 1) {
+
+				t.Errorf("failure point %v, expected the block at (4, 1)",
+					res.FailurePoint)
+			}
+		},
+	},
+	{
+		name:  "the holes of a color must have room for all its blocks",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 9, 0, 3, 0, 1,
+			1, 10, 1, 2, 1, 1,
+			1, 10, 1, 1, 1, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		setup: func(b *Board) (*Board, error) {
+
+			return withCapacity(1)(b.WithPlugging(true))
+		},
+		dir:     DirRight,
+		outcome: OutcomeFailed,
+	},
+	{
+		name:  "the holes without a capacity take any number of blocks",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 9, 0, 3, 0, 1,
+			1, 10, 1, 2, 1, 1,
+			1, 10, 1, 1, 1, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		setup:   withPlugs,
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+	},
+}
+
+func TestApplyPlugs(t *testing.T) {
+
+	runApplyCases(t, plugCases)
+}
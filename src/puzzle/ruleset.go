@@ -0,0 +1,74 @@
+package puzzle
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Ruleset : How far the blocks move on each move
+type Ruleset int32
+
+// Rulesets
+const (
+	RulesetSlide Ruleset = 0 // Until something stops them
+	RulesetStep  Ruleset = 1 // One tile only
+)
+
+// The names the stage files can use
+var rulesets = map[string]Ruleset{
+	"slide": RulesetSlide,
+	"step":  RulesetStep,
+}
+
+// RulesetNames : The names ParseRuleset accepts, sorted
+func RulesetNames() []string {
+
+	names := make([]string, 0, len(rulesets))
+	for n := range rulesets {
+
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseRuleset : Get a ruleset by its name
+func ParseRuleset(name string) (Ruleset, error) {
+
+	r, ok := rulesets[name]
+	if !ok {
+
+		return RulesetSlide, fmt.Errorf("unknown ruleset \"%s\"", name)
+	}
+	return r, nil
+}
+
+// String : Name of the ruleset
+func (r Ruleset) String() string {
+
+	for n, v := range rulesets {
+
+		if v == r {
+
+			return n
+		}
+	}
+	return "unknown"
+}
+
+// WithRuleset : Returns a copy of the board with
+// the given ruleset
+func (b *Board) WithRuleset(r Ruleset) *Board {
+
+	out := b.clone()
+	out.ruleset = r
+
+	return out
+}
+
+// Ruleset : Getter for ruleset
+func (b *Board) Ruleset() Ruleset {
+
+	return b.ruleset
+}
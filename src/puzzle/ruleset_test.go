@@ -0,0 +1,117 @@
+package puzzle
+
+import "testing"
+
+func withSteps(b *Board) (*Board, error) {
+
+	return b.WithRuleset(RulesetStep), nil
+}
+
+var stepCases = []applyCase{
+	{
+		name:  "every block moves one tile",
+		width: 6,
+		layer: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 10, 0, 0, 0, 1,
+			1, 0, 0, 11, 0, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+		setup:   withSteps,
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1, 1,
+			1, 0, 10, 0, 0, 1,
+			1, 0, 0, 0, 11, 1,
+			1, 1, 1, 1, 1, 1,
+		},
+	},
+	{
+		name:  "a block may still drop to a hole",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 2, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withSteps,
+		dir:     DirRight,
+		outcome: OutcomeCleared,
+	},
+	{
+		name:  "arriving next to a cracked wall is not a hit",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 10, 0, 35, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withSteps,
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 1, 1, 1, 1,
+			1, 0, 10, 35, 1,
+			1, 1, 1, 1, 1,
+		},
+		check: func(t *testing.T, res *Result) {
+
+			if n := res.Board.HitsLeft(3, 1); n != 1 {
+
+				t.Errorf("%d hits left, expected 1", n)
+			}
+			if len(res.Steps) != 1 || res.Steps[0].Moves[0].Event != EventNone {
+
+				t.Error("the block stopped with an event")
+			}
+		},
+	},
+	{
+		name:  "a bomb explodes after its step",
+		width: 5,
+		layer: []int32{
+			1, 1, 1, 1, 1,
+			1, 40, 0, 0, 1,
+			1, 1, 1, 1, 1,
+			1, 10, 0, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+		setup:   withSteps,
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			1, 0, 0, 0, 1,
+			1, 0, 0, 0, 1,
+			1, 0, 0, 0, 1,
+			1, 0, 10, 0, 1,
+			1, 1, 1, 1, 1,
+		},
+	},
+}
+
+func TestApplySteps(t *testing.T) {
+
+	runApplyCases(t, stepCases)
+}
+
+func TestParseRuleset(t *testing.T) {
+
+	for _, name := range RulesetNames() {
+
+		r, err := ParseRuleset(name)
+		if err != nil {
+
+			t.Fatal(err)
+		}
+		if r.String() != name {
+
+			t.Errorf("%s parses back to %s", name, r.String())
+		}
+	}
+
+	if _, err := ParseRuleset("teleport"); err == nil {
+
+		t.Error("unknown rulesets are accepted")
+	}
+}
@@ -0,0 +1,114 @@
+package puzzle
+
+import (
+	"errors"
+)
+
+// Errors the solver may return
+var (
+	ErrNoSolution = errors.New("the stage cannot be solved")
+	ErrStateLimit = errors.New("too many states to search")
+)
+
+// Solution : The shortest sequence of moves that
+// clears a board
+type Solution struct {
+	Moves  []Direction
+	Groups []int32 // The active color for each move in the group mode, nil otherwise
+	States int32   // How many states were visited
+}
+
+type solverNode struct {
+	board  *Board
+	parent int32
+	dir    Direction
+	group  int32
+}
+
+// Solve : Find the shortest solution with a breadth-first
+// search over the board states. If maxStates is positive,
+// give up after visiting that many states
+func Solve(b *Board, maxStates int32) (*Solution, error) {
+
+	if b.Cleared() {
+
+		return &Solution{Moves: make([]Direction, 0), States: 1}, nil
+	}
+
+	nodes := []solverNode{{board: b, parent: -1, dir: DirNone}}
+	visited := map[string]bool{b.Key(): true}
+
+	var res *Result
+	var key string
+	var board *Board
+
+	// In the group mode, changing the color is free,
+	// so each color and direction is a move of its own
+	for i := int32(0); i < int32(len(nodes)); i++ {
+
+		for _, g := range nodes[i].board.moveGroups() {
+
+			board = nodes[i].board
+			if board.groupMode && board.active != g {
+
+				board = board.WithActive(g)
+			}
+
+			for _, dir := range Directions {
+
+				res = board.Apply(dir)
+				if res.Outcome == OutcomeNone || res.Outcome == OutcomeFailed ||
+					res.Outcome == OutcomeLoop {
+
+					continue
+				}
+
+				if res.Outcome == OutcomeCleared {
+
+					return tracePath(nodes, i, dir, g), nil
+				}
+
+				key = res.Board.Key()
+				if visited[key] {
+
+					continue
+				}
+				visited[key] = true
+
+				nodes = append(nodes,
+					solverNode{board: res.Board, parent: i, dir: dir, group: g})
+				if maxStates > 0 && int32(len(nodes)) >= maxStates {
+
+					return nil, ErrStateLimit
+				}
+			}
+		}
+	}
+
+	return nil, ErrNoSolution
+}
+
+func tracePath(nodes []solverNode, last int32, dir Direction, group int32) *Solution {
+
+	moves := []Direction{dir}
+	groups := []int32{group}
+	for i := last; nodes[i].parent >= 0; i = nodes[i].parent {
+
+		moves = append(moves, nodes[i].dir)
+		groups = append(groups, nodes[i].group)
+	}
+
+	// Reverse, since we started from the end
+	for i, j := 0, len(moves)-1; i < j; i, j = i+1, j-1 {
+
+		moves[i], moves[j] = moves[j], moves[i]
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+
+	sol := &Solution{Moves: moves, States: int32(len(nodes))}
+	if nodes[0].board.groupMode {
+
+		sol.Groups = groups
+	}
+	return sol
+}
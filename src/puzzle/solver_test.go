@@ -0,0 +1,85 @@
+package puzzle
+
+import "testing"
+
+func TestSolve(t *testing.T) {
+
+	cases := []struct {
+		name  string
+		width int32
+		layer []int32
+		setup func(b *Board) (*Board, error) // Optional
+		moves int
+	}{
+		{
+			name:  "cleared already",
+			width: 3,
+			layer: []int32{1, 0, 1},
+			moves: 0,
+		},
+		{
+			name:  "one slide",
+			width: 5,
+			layer: []int32{
+				1, 1, 1, 1, 1,
+				1, 10, 0, 2, 1,
+				1, 1, 1, 1, 1,
+			},
+			moves: 1,
+		},
+		{
+			name:  "walls destroyed by a bomb are a part of the state",
+			width: 4,
+			layer: []int32{
+				0, 1, 1, 0,
+				0, 2, 0, 1,
+				1, 0, 40, 0,
+				0, 0, 0, 0,
+				10, 10, 1, 1,
+				0, 1, 1, 0,
+			},
+			setup: withTopology("none"),
+			moves: 6,
+		},
+	}
+
+	for _, tc := range cases {
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			b, err := NewBoard(tc.width, int32(len(tc.layer))/tc.width, tc.layer)
+			if err != nil {
+
+				t.Fatal(err)
+			}
+			if tc.setup != nil {
+
+				b, err = tc.setup(b)
+				if err != nil {
+
+					t.Fatal(err)
+				}
+			}
+
+			sol, err := Solve(b, 0)
+			if err != nil {
+
+				t.Fatal(err)
+			}
+			if len(sol.Moves) != tc.moves {
+
+				t.Errorf("solved in %d moves, expected %d", len(sol.Moves), tc.moves)
+			}
+
+			// Playing the solution must clear the stage
+			for _, d := range sol.Moves {
+
+				b = b.Apply(d).Board
+			}
+			if !b.Cleared() {
+
+				t.Error("the solution does not clear the stage")
+			}
+		})
+	}
+}
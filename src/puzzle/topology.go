@@ -0,0 +1,169 @@
+package puzzle
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Edge : What happens to a block that crosses a pair
+// of opposite edges of the stage
+type Edge int32
+
+// Edges
+const (
+	EdgeWrap    Edge = 0 // Comes out from the opposite edge
+	EdgeWall    Edge = 1 // The edge acts as a wall
+	EdgeTwisted Edge = 2 // Like wrap, but the other coordinate is mirrored
+)
+
+// Topology : How the left and right edges (Horizontal)
+// and the top and bottom edges (Vertical) are glued
+// together. The zero value is a torus
+type Topology struct {
+	Horizontal Edge
+	Vertical   Edge
+}
+
+// The names the stage files can use
+var topologies = map[string]Topology{
+	"none":            {EdgeWall, EdgeWall},
+	"horizontal":      {EdgeWrap, EdgeWall},
+	"vertical":        {EdgeWall, EdgeWrap},
+	"torus":           {EdgeWrap, EdgeWrap},
+	"mobius":          {EdgeTwisted, EdgeWall},
+	"mobius-vertical": {EdgeWall, EdgeTwisted},
+	"klein":           {EdgeTwisted, EdgeWrap},
+	"klein-vertical":  {EdgeWrap, EdgeTwisted},
+	"projective":      {EdgeTwisted, EdgeTwisted},
+}
+
+// TopologyNames : The names ParseTopology accepts, sorted
+func TopologyNames() []string {
+
+	names := make([]string, 0, len(topologies))
+	for n := range topologies {
+
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseTopology : Get a topology by its name
+func ParseTopology(name string) (Topology, error) {
+
+	t, ok := topologies[name]
+	if !ok {
+
+		return Topology{}, fmt.Errorf("unknown topology \"%s\"", name)
+	}
+	return t, nil
+}
+
+// String : Name of the topology
+func (t Topology) String() string {
+
+	for n, v := range topologies {
+
+		if v == t {
+
+			return n
+		}
+	}
+	return "unknown"
+}
+
+// Wraps one coordinate that may have crossed an edge.
+// Returns the new coordinate and if the other one
+// needs to be mirrored
+func (e Edge) apply(v, size int32) (int32, bool, bool) {
+
+	if v >= 0 && v < size {
+
+		return v, false, true
+	}
+
+	switch e {
+
+	case EdgeWall:
+		return v, false, false
+
+	case EdgeTwisted:
+		return negMod(v, size), true, true
+
+	default:
+		break
+	}
+	return negMod(v, size), false, true
+}
+
+// WithTopology : Returns a copy of the board with
+// the given topology
+func (b *Board) WithTopology(t Topology) *Board {
+
+	out := b.clone()
+	out.topology = t
+
+	return out
+}
+
+// Topology : Getter for topology
+func (b *Board) Topology() Topology {
+
+	return b.topology
+}
+
+// The tiles around the given one, the tile itself included,
+// following the topology. Tiles beyond the edges are left out
+func (b *Board) area(p Point) []Point {
+
+	out := make([]Point, 0, 9)
+	seen := make(map[Point]bool)
+
+	for dy := int32(-1); dy <= 1; dy++ {
+
+		row, ok := b.Neighbour(p, NewPoint(0, dy))
+		if !ok {
+			continue
+		}
+
+		for dx := int32(-1); dx <= 1; dx++ {
+
+			q, ok := b.Neighbour(row, NewPoint(dx, 0))
+			if ok && !seen[q] {
+
+				seen[q] = true
+				out = append(out, q)
+			}
+		}
+	}
+	return out
+}
+
+// Neighbour : The tile one step to the given direction
+// from the given tile, following the topology. Returns
+// false if an edge is in the way
+func (b *Board) Neighbour(p Point, d Point) (Point, bool) {
+
+	x, mirrorY, ok := b.topology.Horizontal.apply(p.X+d.X, b.width)
+	if !ok {
+
+		return p, false
+	}
+	y, mirrorX, ok := b.topology.Vertical.apply(p.Y+d.Y, b.height)
+	if !ok {
+
+		return p, false
+	}
+
+	if mirrorX {
+
+		x = b.width - 1 - x
+	}
+	if mirrorY {
+
+		y = b.height - 1 - y
+	}
+	return NewPoint(x, y), true
+}
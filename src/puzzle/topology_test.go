@@ -0,0 +1,100 @@
+package puzzle
+
+import "testing"
+
+// Without a topology, the boards are tori
+func withTopology(name string) func(b *Board) (*Board, error) {
+
+	return func(b *Board) (*Board, error) {
+
+		t, err := ParseTopology(name)
+		if err != nil {
+
+			return nil, err
+		}
+		return b.WithTopology(t), nil
+	}
+}
+
+var topologyCases = []applyCase{
+	{
+		name:  "the edges are walls",
+		width: 4,
+		layer: []int32{
+			0, 10, 0, 0,
+		},
+		setup:   withTopology("none"),
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			0, 0, 0, 10,
+		},
+	},
+	{
+		name:  "the side edges are walls when only the vertical ones wrap",
+		width: 3,
+		layer: []int32{
+			0, 10, 1,
+			0, 0, 1,
+			1, 1, 1,
+		},
+		setup:   withTopology("vertical"),
+		dir:     DirLeft,
+		outcome: OutcomeMoved,
+		want: []int32{
+			10, 0, 1,
+			0, 0, 1,
+			1, 1, 1,
+		},
+	},
+	{
+		name:  "a twisted edge mirrors the other coordinate",
+		width: 3,
+		layer: []int32{
+			0, 1, 10,
+			1, 1, 1,
+			0, 1, 1,
+		},
+		setup:   withTopology("mobius"),
+		dir:     DirRight,
+		outcome: OutcomeMoved,
+		want: []int32{
+			0, 1, 0,
+			1, 1, 1,
+			10, 1, 1,
+		},
+		check: func(t *testing.T, res *Result) {
+
+			if !res.Steps[0].Moves[0].Wrapped {
+
+				t.Error("the move did not wrap")
+			}
+		},
+	},
+}
+
+func TestApplyTopologies(t *testing.T) {
+
+	runApplyCases(t, topologyCases)
+}
+
+func TestParseTopology(t *testing.T) {
+
+	for _, name := range TopologyNames() {
+
+		top, err := ParseTopology(name)
+		if err != nil {
+
+			t.Fatal(err)
+		}
+		if top.String() != name {
+
+			t.Errorf("%s parses back to %s", name, top.String())
+		}
+	}
+
+	if _, err := ParseTopology("sphere"); err == nil {
+
+		t.Error("unknown topologies are accepted")
+	}
+}
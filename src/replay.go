@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jani-nykanen/blocked/src/core"
+)
+
+const (
+	replayFolder  = "replays"
+	replayMagic   = "BLKR"
+	replayVersion = 1
+)
+
+// Recorded action states of a single attempt of a stage
+type replay struct {
+	stageID   int32
+	stageHash [sha256.Size]byte
+	actions   []string
+	frames    [][]core.State
+}
+
+func hashStageFile(index int32) ([sha256.Size]byte, error) {
+
+	data, err := ioutil.ReadFile(stageFilePath(index))
+	if err != nil {
+
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+func (rep *replay) record(input *core.InputManager) {
+
+	rep.frames = append(rep.frames, input.GetActionStates())
+}
+
+func (rep *replay) frameCount() int32 {
+
+	return int32(len(rep.frames))
+}
+
+// Returns the states of a frame in the order of the
+// given action names, in case the key configuration
+// has changed since recording
+func (rep *replay) getFrame(frame int32, names []string) []core.State {
+
+	states := make([]core.State, len(names))
+	if frame < 0 || frame >= rep.frameCount() {
+
+		return states
+	}
+
+	for i, n := range names {
+
+		for j, a := range rep.actions {
+
+			if a == n {
+
+				states[i] = rep.frames[frame][j]
+				break
+			}
+		}
+	}
+	return states
+}
+
+func (rep *replay) checkStageFile() bool {
+
+	hash, err := hashStageFile(rep.stageID)
+
+	return err == nil && hash == rep.stageHash
+}
+
+func (rep *replay) saveToFile(path string) error {
+
+	file, err := os.Create(path)
+	if err != nil {
+
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+
+	w.WriteString(replayMagic)
+	w.WriteByte(replayVersion)
+	binary.Write(w, binary.LittleEndian, rep.stageID)
+	w.Write(rep.stageHash[:])
+
+	w.WriteByte(byte(len(rep.actions)))
+	for _, a := range rep.actions {
+
+		w.WriteByte(byte(len(a)))
+		w.WriteString(a)
+	}
+
+	binary.Write(w, binary.LittleEndian, uint32(len(rep.frames)))
+	for _, f := range rep.frames {
+
+		for _, s := range f {
+
+			w.WriteByte(byte(s))
+		}
+	}
+
+	return w.Flush()
+}
+
+// Save to the replay folder with a name that tells
+// the stage and the time
+func (rep *replay) save() (string, error) {
+
+	err := os.MkdirAll(replayFolder, 0755)
+	if err != nil {
+
+		return "", err
+	}
+
+	path := replayFolder + "/stage" + strconv.Itoa(int(rep.stageID)) + "_" +
+		time.Now().Format("20060102_150405") + ".rep"
+
+	return path, rep.saveToFile(path)
+}
+
+func readReplayFile(path string) (*replay, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+
+	header := make([]byte, len(replayMagic)+1)
+	_, err = io.ReadFull(r, header)
+	if err != nil {
+
+		return nil, err
+	}
+	if string(header[:len(replayMagic)]) != replayMagic ||
+		header[len(replayMagic)] != replayVersion {
+
+		return nil, errors.New("not a replay file, or an unsupported version")
+	}
+
+	rep := new(replay)
+
+	err = binary.Read(r, binary.LittleEndian, &rep.stageID)
+	if err != nil {
+
+		return nil, err
+	}
+	_, err = io.ReadFull(r, rep.stageHash[:])
+	if err != nil {
+
+		return nil, err
+	}
+
+	count, err := r.ReadByte()
+	if err != nil {
+
+		return nil, err
+	}
+
+	var length byte
+	var name []byte
+	rep.actions = make([]string, count)
+	for i := range rep.actions {
+
+		length, err = r.ReadByte()
+		if err != nil {
+
+			return nil, err
+		}
+
+		name = make([]byte, length)
+		_, err = io.ReadFull(r, name)
+		if err != nil {
+
+			return nil, err
+		}
+		rep.actions[i] = string(name)
+	}
+
+	var frameCount uint32
+	err = binary.Read(r, binary.LittleEndian, &frameCount)
+	if err != nil {
+
+		return nil, err
+	}
+
+	data := make([]byte, int(frameCount)*len(rep.actions))
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+
+		return nil, err
+	}
+
+	rep.frames = make([][]core.State, frameCount)
+	for i := range rep.frames {
+
+		rep.frames[i] = make([]core.State, len(rep.actions))
+		for j := range rep.frames[i] {
+
+			rep.frames[i][j] = core.State(data[i*len(rep.actions)+j])
+		}
+	}
+
+	return rep, nil
+}
+
+func newReplay(stageID int32, actions []string) *replay {
+
+	rep := new(replay)
+
+	rep.stageID = stageID
+	rep.actions = actions
+	rep.frames = make([][]core.State, 0)
+
+	// If this fails, the hash is left zero and the
+	// viewer will complain about it
+	rep.stageHash, _ = hashStageFile(stageID)
+
+	return rep
+}
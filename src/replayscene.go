@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jani-nykanen/blocked/src/core"
+)
+
+const (
+	replayFastForwardSpeed int32 = 4
+)
+
+// Plays back a recorded attempt by feeding the recorded
+// action states to a game scene
+type replayScene struct {
+	path   string
+	rep    *replay
+	game   *gameScene
+	frame  int32
+	paused bool
+}
+
+func (rs *replayScene) Activate(ev *core.Event, param interface{}) error {
+
+	var err error
+
+	rs.rep, err = readReplayFile(rs.path)
+	if err != nil {
+
+		return err
+	}
+
+	if !rs.rep.checkStageFile() {
+
+		fmt.Printf("Warning: stage %d has changed since the replay was recorded\n",
+			rs.rep.stageID)
+	}
+
+	cinfo := newCompletionInfo()
+	cinfo.currentStage = rs.rep.stageID
+
+	rs.game = new(gameScene)
+	rs.game.playback = true
+
+	rs.frame = 0
+	rs.paused = false
+
+	return rs.game.Activate(ev, cinfo)
+}
+
+func (rs *replayScene) Refresh(ev *core.Event) {
+
+	if ev.Transition.Active() {
+		return
+	}
+
+	if ev.Input.GetActionState("back") == core.StatePressed {
+
+		ev.Terminate(nil)
+		return
+	}
+
+	if ev.Input.GetActionState("start") == core.StatePressed {
+
+		rs.paused = !rs.paused
+	}
+
+	count := int32(1)
+	if rs.paused {
+
+		count = 0
+		if ev.Input.GetActionState("select") == core.StatePressed {
+
+			count = 1
+		}
+
+	} else if ev.Input.GetActionState("right")&core.StateDownOrPressed == 1 {
+
+		count = replayFastForwardSpeed
+	}
+
+	// The viewer itself needs the live states, so
+	// put them back afterwards
+	live := ev.Input.GetActionStates()
+	names := ev.Input.ActionNames()
+
+	for i := int32(0); i < count; i++ {
+
+		// Frames are recorded only when the game does
+		// not ignore the input, see gameScene.Refresh
+		if rs.game.frameTransition.Active() {
+
+			ev.Input.SetActionStates(make([]core.State, len(names)))
+
+		} else {
+
+			ev.Input.SetActionStates(rs.rep.getFrame(rs.frame, names))
+			if rs.frame < rs.rep.frameCount() {
+
+				rs.frame++
+			}
+		}
+		rs.game.Refresh(ev)
+	}
+
+	ev.Input.SetActionStates(live)
+}
+
+func (rs *replayScene) Redraw(c *core.Canvas, ap *core.AssetPack) {
+
+	rs.game.Redraw(c, ap)
+
+	status := "PLAY"
+	if rs.frame >= rs.rep.frameCount() {
+
+		status = "END"
+
+	} else if rs.paused {
+
+		status = "PAUSE"
+	}
+	text := status + " " + strconv.Itoa(int(rs.frame)) + "/" +
+		strconv.Itoa(int(rs.rep.frameCount()))
+
+	c.MoveTo(0, 0)
+	c.ResetViewport()
+
+	width := int32(len(text))*8 + 8
+	c.FillRect(int32(c.Width())/2-width/2, 16, width, 12,
+		core.NewRGBA(0, 0, 0, 170))
+	c.DrawText(ap.GetAsset("font").(*core.Bitmap), text,
+		int32(c.Width())/2, 18, 0, 0, true)
+}
+
+func (rs *replayScene) Dispose() interface{} {
+
+	if rs.game != nil {
+
+		rs.game.Dispose()
+	}
+	return nil
+}
+
+func newReplayScene(path string) core.Scene {
+
+	rs := new(replayScene)
+
+	rs.path = path
+
+	return rs
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"github.com/jani-nykanen/blocked/src/core"
+	"github.com/jani-nykanen/blocked/src/puzzle"
+	"github.com/jani-nykanen/blocked/src/stagefile"
 )
 
 type stage struct {
@@ -14,7 +16,8 @@ type stage struct {
 	difficulty     int32
 	tmap           *core.Tilemap
 	tiles          []int32
-	solid          []int32
+	capacity       []int32       // How many blocks the holes still take
+	board          *puzzle.Board // The initial state
 	width          int32
 	height         int32
 	tileLayer      *core.Bitmap
@@ -22,13 +25,13 @@ type stage struct {
 	tilesDrawn     bool
 	holeSprite     *core.Sprite
 	markerSprite   *core.Sprite
+	teleSprite     *core.Sprite
 	shakeTimer     int32
 }
 
 func (s *stage) reset() {
 
 	s.tilesDrawn = false
-	s.computeInitialSolid()
 
 	s.shakeTimer = 0
 }
@@ -38,23 +41,6 @@ func (s *stage) shake(time int32) {
 	s.shakeTimer = time
 }
 
-func (s *stage) computeInitialSolid() {
-
-	for i, v := range s.tiles {
-
-		switch v {
-
-		case 1:
-			s.solid[i] = 1
-			break
-
-		default:
-			s.solid[i] = 0
-			break
-		}
-	}
-}
-
 func (s *stage) getTile(x, y, def int32) int32 {
 
 	if x < 0 || y < 0 || x >= s.width || y >= s.height {
@@ -64,29 +50,6 @@ func (s *stage) getTile(x, y, def int32) int32 {
 	return s.tiles[y*s.width+x]
 }
 
-func (s *stage) getSolid(x, y int32) int32 {
-
-	x = core.NegMod(x, s.width)
-	y = core.NegMod(y, s.height)
-
-	return s.solid[y*s.width+x]
-}
-
-func (s *stage) updateSolidTile(x, y, newValue int32) {
-
-	x = core.NegMod(x, s.width)
-	y = core.NegMod(y, s.height)
-
-	s.solid[y*s.width+x] = newValue
-}
-
-func (s *stage) checkHoleTile(x, y, id int32) (bool, bool) {
-
-	t := s.getTile(x, y, 0) - 2
-
-	return t >= 0 && t <= 3, t == id
-}
-
 func (s *stage) computeNeighbourhood(tid, dx, dy int32) [9]bool {
 
 	var neighbour [9]bool
@@ -229,6 +192,26 @@ func (s *stage) drawWallTile(c *core.Canvas, bmp *core.Bitmap,
 		8, 8, dx+8, dy+8, core.FlipNone)
 }
 
+// Tells which plates open which gates, if
+// there is more than one channel
+func (s *stage) drawChannelMarker(c *core.Canvas, x, y int32) {
+
+	colors := []core.Color{
+		core.NewRGB(218, 36, 0),
+		core.NewRGB(0, 109, 218),
+		core.NewRGB(72, 182, 0),
+		core.NewRGB(182, 72, 218),
+	}
+
+	ch := s.board.Channel(x, y)
+	if ch <= 0 {
+
+		return
+	}
+
+	c.FillRect(x*16+6, y*16+6, 4, 4, colors[(ch-1)%int32(len(colors))])
+}
+
 func (s *stage) drawTiles(c *core.Canvas, ap *core.AssetPack) {
 
 	var tid int32
@@ -251,7 +234,54 @@ func (s *stage) drawTiles(c *core.Canvas, ap *core.AssetPack) {
 				s.drawWallTile(c, bmp, tid, 0, x, y)
 				break
 
+			case puzzle.TileCrackedWall:
+
+				// Cracked walls only join each other
+				s.drawWallTile(c, bmp, tid, 0, x, y)
+				c.DrawBitmapRegion(bmp, 0, 96, 16, 16,
+					x*16, y*16, core.FlipNone)
+				break
+
+			case puzzle.TilePlugged:
+
+				c.DrawBitmapRegion(bmp, 16, 96, 16, 16,
+					x*16, y*16, core.FlipNone)
+				break
+
 			default:
+
+				if dir := puzzle.ArrowDirection(tid); dir != puzzle.DirNone {
+
+					c.DrawBitmapRegion(bmp, (int32(dir)-1)*16, 32, 16, 16,
+						x*16, y*16, core.FlipNone)
+
+				} else if side := puzzle.FenceSide(tid); side != puzzle.DirNone {
+
+					c.DrawBitmapRegion(bmp, (int32(side)-1)*16, 48, 16, 16,
+						x*16, y*16, core.FlipNone)
+
+				} else if tid == puzzle.TilePlate {
+
+					c.DrawBitmapRegion(bmp, 48, 16, 16, 16,
+						x*16, y*16, core.FlipNone)
+					s.drawChannelMarker(c, x, y)
+
+				} else if tid == puzzle.TileGateClosed || tid == puzzle.TileGateOpen {
+
+					c.DrawBitmapRegion(bmp, (tid-puzzle.TileGateClosed)*16, 64, 16, 16,
+						x*16, y*16, core.FlipNone)
+					s.drawChannelMarker(c, x, y)
+
+				} else if dir := puzzle.ConveyorDirection(tid); dir != puzzle.DirNone {
+
+					c.DrawBitmapRegion(bmp, (int32(dir)-1)*16, 112, 16, 16,
+						x*16, y*16, core.FlipNone)
+
+				} else if id := puzzle.PaintID(tid); id >= 0 {
+
+					c.DrawBitmapRegion(bmp, id*16, 80, 16, 16,
+						x*16, y*16, core.FlipNone)
+				}
 				break
 			}
 		}
@@ -291,7 +321,7 @@ func (s *stage) drawShadows(c *core.Canvas, ap *core.AssetPack) {
 
 		for x := int32(0); x < s.width; x++ {
 
-			if s.getTile(x, y, 0) != 1 {
+			if !puzzle.IsSolidTile(s.getTile(x, y, 0)) {
 
 				continue
 			}
@@ -304,6 +334,7 @@ func (s *stage) refreshTileLayer(c *core.Canvas, ap *core.AssetPack) {
 
 	cb := func(c *core.Canvas, ap *core.AssetPack) {
 
+		c.ClearToAlpha()
 		s.drawTiles(c, ap)
 	}
 	c.DrawToBitmap(s.tileLayer, ap, cb)
@@ -390,18 +421,46 @@ func (s *stage) drawOutlines(c *core.Canvas) {
 	c.SetBitmapColor(s.tileLayer, 255, 255, 255)
 }
 
+// Sticky tiles are a bit lower than the floor around them
+func (s *stage) drawStickyTileShadow(c *core.Canvas, dx, dy int32) {
+
+	const shadowAlpha = 85
+	const shadowWidth = 2
+
+	col := core.NewRGBA(0, 0, 0, shadowAlpha)
+
+	if s.getTile(dx, dy-1, 1) != puzzle.TileSticky {
+
+		c.FillRect(dx*16, dy*16, 16, shadowWidth, col)
+	}
+	if s.getTile(dx-1, dy, 1) != puzzle.TileSticky {
+
+		c.FillRect(dx*16, dy*16, shadowWidth, 16, col)
+	}
+}
+
 func (s *stage) drawBackground(c *core.Canvas, ap *core.AssetPack) {
 
 	var sx int32
+	var tid int32
 	bmp := ap.GetAsset("tileset").(*core.Bitmap)
 	for y := int32(0); y < s.height; y++ {
 
 		for x := int32(0); x < s.width; x++ {
 
-			if s.getTile(x, y, 0) == 1 {
+			tid = s.getTile(x, y, 0)
+			if tid == 1 {
+
+				continue
+			}
 
+			if tid == puzzle.TileSticky {
+
+				c.DrawBitmapRegion(bmp, 32, 16, 16, 16, x*16, y*16, core.FlipNone)
+				s.drawStickyTileShadow(c, x, y)
 				continue
 			}
+
 			sx = 0
 			if x%2 == y%2 {
 				sx = 16
@@ -415,6 +474,7 @@ func (s *stage) drawBackground(c *core.Canvas, ap *core.AssetPack) {
 func (s *stage) drawHoles(c *core.Canvas, ap *core.AssetPack) {
 
 	bmp := ap.GetAsset("holes").(*core.Bitmap)
+	font := ap.GetAsset("font").(*core.Bitmap)
 
 	var tid int32
 
@@ -435,10 +495,28 @@ func (s *stage) drawHoles(c *core.Canvas, ap *core.AssetPack) {
 			c.DrawSpriteFrame(s.holeSprite, bmp,
 				x*16, y*16, 4, tid,
 				core.FlipNone)
+
+			s.drawHoleCapacity(c, font, x, y)
 		}
 	}
 }
 
+// Holes with a limited capacity show how many
+// blocks they still take
+func (s *stage) drawHoleCapacity(c *core.Canvas, font *core.Bitmap, x, y int32) {
+
+	n := s.capacity[y*s.width+x]
+	if n <= 0 {
+		return
+	}
+	text := strconv.Itoa(int(n))
+
+	c.SetBitmapColor(font, 0, 0, 0)
+	c.DrawText(font, text, x*16+9, y*16+5, -1, 0, true)
+	c.SetBitmapColor(font, 255, 255, 255)
+	c.DrawText(font, text, x*16+8, y*16+4, -1, 0, true)
+}
+
 func (s *stage) preDraw(c *core.Canvas, ap *core.AssetPack) {
 
 	if !s.tilesDrawn {
@@ -450,6 +528,28 @@ func (s *stage) preDraw(c *core.Canvas, ap *core.AssetPack) {
 	}
 }
 
+func (s *stage) drawTeleporters(c *core.Canvas, ap *core.AssetPack) {
+
+	bmp := ap.GetAsset("teleporters").(*core.Bitmap)
+
+	var tid int32
+
+	for y := int32(0); y < s.height; y++ {
+
+		for x := int32(0); x < s.width; x++ {
+
+			tid = s.getTile(x, y, 0)
+			if !puzzle.IsTeleporter(tid) {
+				continue
+			}
+
+			c.DrawSpriteFrame(s.teleSprite, bmp,
+				x*16, y*16, s.teleSprite.Frame(),
+				tid-puzzle.TileTeleporterFirst, core.FlipNone)
+		}
+	}
+}
+
 // That is, draw after objects
 func (s *stage) postDraw(c *core.Canvas, ap *core.AssetPack) {
 
@@ -494,6 +594,7 @@ func (s *stage) draw(c *core.Canvas, ap *core.AssetPack) {
 
 	// Holes
 	s.drawHoles(c, ap)
+	s.drawTeleporters(c, ap)
 
 }
 
@@ -511,6 +612,7 @@ func (s *stage) update(ev *core.Event) {
 
 	const holeAnimSpeed = 6
 	const markerAnimSpeed = 15
+	const teleAnimSpeed = 8
 
 	if s.shakeTimer > 0 {
 
@@ -520,6 +622,7 @@ func (s *stage) update(ev *core.Event) {
 
 		s.holeSprite.Animate(0, 0, 3, holeAnimSpeed, ev.Step())
 		s.markerSprite.Animate(0, 0, 3, markerAnimSpeed, ev.Step())
+		s.teleSprite.Animate(0, 0, 3, teleAnimSpeed, ev.Step())
 	}
 }
 
@@ -550,35 +653,41 @@ func (s *stage) dispose() {
 	s.shadowLayer.Dispose()
 }
 
-func (s *stage) parseObjects(objm *objectManager) {
+// Gates open and close when the blocks move, and
+// undoing a move changes the tiles back
+func (s *stage) syncTiles(objm *objectManager) {
 
-	var tid int32
+	if !objm.tilesChanged {
 
-	for y := int32(0); y < s.height; y++ {
-
-		for x := int32(0); x < s.width; x++ {
+		return
+	}
+	copy(s.tiles, objm.tiles)
+	copy(s.capacity, objm.capacity)
 
-			tid = s.getTile(x, y, 0)
-			if tid >= 9 {
+	s.tilesDrawn = false
+	objm.tilesChanged = false
+}
 
-				objm.addBlock(x, y, tid-9)
+func (s *stage) parseObjects(objm *objectManager) {
 
-				s.updateSolidTile(x, y, 2)
-			}
-		}
-	}
+	objm.setBoard(s.board)
 }
 
-func newStage(mapIndex int32, ev *core.Event) (*stage, error) {
+func stageFilePath(mapIndex int32) string {
 
 	const basePath = "assets/maps/"
 
+	return basePath + strconv.Itoa(int(mapIndex)) + ".tmx"
+}
+
+func newStage(mapIndex int32, ev *core.Event) (*stage, error) {
+
 	s := new(stage)
 	var err error
 
 	s.id = mapIndex
 
-	s.tmap, err = core.ParseTMX(basePath + strconv.Itoa(int(mapIndex)) + ".tmx")
+	s.tmap, err = core.ParseTMX(stageFilePath(mapIndex))
 	if err != nil {
 
 		return nil, err
@@ -602,22 +711,23 @@ func newStage(mapIndex int32, ev *core.Event) (*stage, error) {
 		return nil, err
 	}
 
-	s.tiles, err = s.tmap.CloneLayer(0)
+	s.width = s.tmap.Width()
+	s.height = s.tmap.Height()
+
+	s.board, err = stagefile.NewBoard(s.tmap)
 	if err != nil {
 
 		return nil, err
 	}
-
-	s.width = s.tmap.Width()
-	s.height = s.tmap.Height()
+	// Blocks are objects, not tiles
+	s.tiles = s.board.Tiles()
+	s.capacity = s.board.Capacities()
 
 	s.tilesDrawn = false
 
-	s.solid = make([]int32, s.width*s.height)
-	s.computeInitialSolid()
-
 	s.holeSprite = core.NewSprite(16, 16)
 	s.markerSprite = core.NewSprite(24, 24)
+	s.teleSprite = core.NewSprite(16, 16)
 
 	return s, err
 }
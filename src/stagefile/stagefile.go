@@ -0,0 +1,252 @@
+// Package stagefile turns the TMX files of the stages
+// to puzzle boards, the same way for the game and
+// for the tools
+package stagefile
+
+import (
+	"math"
+	"strings"
+
+	"github.com/jani-nykanen/blocked/src/core"
+	"github.com/jani-nykanen/blocked/src/puzzle"
+)
+
+// Objects with this property set the channel of the
+// plates and the gates they cover
+const channelProperty = "channel"
+
+// The map property that selects the topology, see
+// puzzle.ParseTopology. Stages without it are tori
+const wrapProperty = "wrap"
+
+// How far the blocks move, see puzzle.ParseRuleset.
+// Stages without it use the sliding rules
+const rulesetProperty = "ruleset"
+
+// Objects with this property join the blocks they cover
+// to a multi-cell block. Objects with the same value form
+// a single block, so that shapes like L are possible
+const pieceProperty = "piece"
+
+// How many hits the cracked walls take. The map property
+// sets it for the whole stage, objects for the walls they
+// cover
+const hitsProperty = "hits"
+
+// When this map property is true, only the blocks of
+// the selected color move
+const groupsProperty = "groups"
+
+// When this map property is true, the neutral blocks
+// fill the holes they enter
+const plugsProperty = "plugs"
+
+// Objects with this property limit how many blocks
+// the holes they cover take
+const capacityProperty = "capacity"
+
+// Objects with this property change how the blocks they
+// cover move, see puzzle.ParseVariant
+const variantProperty = "variant"
+
+// Channels : Reads the channel of each tile from the
+// objects. Tiles without an object get channel 0
+func Channels(tmap *core.Tilemap) []int32 {
+
+	return tileValues(tmap, channelProperty)
+}
+
+// Pieces : Reads the piece number of each tile from the
+// objects. Tiles without an object get piece 0, which
+// means that the block there is a single-cell one
+func Pieces(tmap *core.Tilemap) []int32 {
+
+	return tileValues(tmap, pieceProperty)
+}
+
+// WallHits : Reads the number of hits each cracked
+// wall takes before it breaks
+func WallHits(tmap *core.Tilemap) []int32 {
+
+	def := tmap.GetNumericProperty(hitsProperty, 1)
+
+	hits := tileValues(tmap, hitsProperty)
+	for i, v := range hits {
+
+		if v <= 0 {
+
+			hits[i] = def
+		}
+	}
+	return hits
+}
+
+// Gives each tile the value of the property of the
+// objects that cover it
+func tileValues(tmap *core.Tilemap, key string) []int32 {
+
+	values := make([]int32, tmap.Width()*tmap.Height())
+	coverTiles(tmap, key, func(i int32, o *core.TilemapObject) {
+
+		values[i] = o.GetNumericProperty(key, 0)
+	})
+
+	return values
+}
+
+// Calls the function for each tile covered by an
+// object that has the property
+func coverTiles(tmap *core.Tilemap, key string,
+	cb func(i int32, o *core.TilemapObject)) {
+
+	tw := float64(tmap.TileWidth())
+	th := float64(tmap.TileHeight())
+	if tw <= 0 || th <= 0 {
+
+		tw, th = 16, 16
+	}
+
+	var left, top, right, bottom int32
+	objects := tmap.Objects()
+	for k := range objects {
+
+		o := &objects[k]
+		if !o.HasProperty(key) {
+			continue
+		}
+
+		left = int32(math.Floor(float64(o.X) / tw))
+		top = int32(math.Floor(float64(o.Y) / th))
+
+		// Point objects cover the tile they are in
+		right = core.MaxInt32(left+1, int32(math.Ceil(float64(o.X+o.Width)/tw)))
+		bottom = core.MaxInt32(top+1, int32(math.Ceil(float64(o.Y+o.Height)/th)))
+
+		for y := core.MaxInt32(0, top); y < core.MinInt32(bottom, tmap.Height()); y++ {
+
+			for x := core.MaxInt32(0, left); x < core.MinInt32(right, tmap.Width()); x++ {
+
+				cb(y*tmap.Width()+x, o)
+			}
+		}
+	}
+}
+
+// Variants : Reads the variant of the block on each tile
+// from the objects. Tiles without an object get the
+// normal variant
+func Variants(tmap *core.Tilemap) ([]puzzle.Variant, error) {
+
+	var err error
+	variants := make([]puzzle.Variant, tmap.Width()*tmap.Height())
+
+	coverTiles(tmap, variantProperty, func(i int32, o *core.TilemapObject) {
+
+		v, e := puzzle.ParseVariant(strings.TrimSpace(o.GetProperty(variantProperty, "")))
+		if e != nil {
+
+			err = e
+		}
+		variants[i] = v
+	})
+
+	return variants, err
+}
+
+// NewBoard : Builds the initial board of a parsed stage
+func NewBoard(tmap *core.Tilemap) (*puzzle.Board, error) {
+
+	layer, err := tmap.CloneLayer(0)
+	if err != nil {
+
+		return nil, err
+	}
+
+	board, err := puzzle.NewBoard(tmap.Width(), tmap.Height(), layer)
+	if err != nil {
+
+		return nil, err
+	}
+
+	topology, err := puzzle.ParseTopology(
+		strings.TrimSpace(tmap.GetProperty(wrapProperty, "torus")))
+	if err != nil {
+
+		return nil, err
+	}
+
+	ruleset, err := puzzle.ParseRuleset(
+		strings.TrimSpace(tmap.GetProperty(rulesetProperty, "slide")))
+	if err != nil {
+
+		return nil, err
+	}
+
+	board, err = board.WithTopology(topology).
+		WithRuleset(ruleset).
+		WithPieces(Pieces(tmap))
+	if err != nil {
+
+		return nil, err
+	}
+
+	variants, err := Variants(tmap)
+	if err != nil {
+
+		return nil, err
+	}
+
+	board, err = board.WithVariants(variants)
+	if err != nil {
+
+		return nil, err
+	}
+
+	board, err = board.WithWallHits(WallHits(tmap))
+	if err != nil {
+
+		return nil, err
+	}
+
+	board, err = board.WithHoleCapacity(tileValues(tmap, capacityProperty))
+	if err != nil {
+
+		return nil, err
+	}
+
+	if isSet(tmap, groupsProperty) {
+
+		board = board.WithGroupMode(true)
+	}
+	if isSet(tmap, plugsProperty) {
+
+		board = board.WithPlugging(true)
+	}
+
+	return board.WithChannels(Channels(tmap))
+}
+
+// Tells if a map property is "true" or "1"
+func isSet(tmap *core.Tilemap, key string) bool {
+
+	v := strings.TrimSpace(tmap.GetProperty(key, "false"))
+	return v == "true" || v == "1"
+}
+
+// Load : Parses a stage file and builds its board
+func Load(path string) (*core.Tilemap, *puzzle.Board, error) {
+
+	tmap, err := core.ParseTMX(path)
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	board, err := NewBoard(tmap)
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	return tmap, board, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/jani-nykanen/blocked/src/puzzle"
+)
+
+type generatorParams struct {
+	width         int32
+	height        int32
+	colors        int32
+	blocksPerHole int32
+	neutral       int32
+	wallDensity   float64
+	minMoves      int32
+	maxMoves      int32
+	stateLimit    int32
+	ruleset       puzzle.Ruleset
+}
+
+type generatedStage struct {
+	layer      []int32
+	width      int32
+	height     int32
+	name       string
+	moves      int32
+	difficulty int32
+	ruleset    puzzle.Ruleset
+}
+
+var nameAdjectives = []string{
+	"Tiny", "Crooked", "Silent", "Busy", "Narrow", "Twisted",
+	"Hidden", "Lonely", "Frozen", "Endless", "Quiet", "Tight",
+}
+
+var nameNouns = []string{
+	"Corner", "Maze", "Garden", "Road", "Tunnel", "Yard",
+	"Market", "Bridge", "Square", "Harbor", "Alley", "Loop",
+}
+
+func randomName(rnd *rand.Rand) string {
+
+	return nameAdjectives[rnd.Intn(len(nameAdjectives))] + " " +
+		nameNouns[rnd.Intn(len(nameNouns))]
+}
+
+// Roughly the same scale as in the hand-made stages
+func computeDifficulty(moves int32) int32 {
+
+	switch {
+
+	case moves <= 9:
+		return 1
+
+	case moves <= 13:
+		return 2
+
+	case moves <= 17:
+		return 3
+
+	default:
+		break
+	}
+	return 4
+}
+
+// Picks a random tile that is still floor, returns -1
+// if there are none left
+func randomFloorTile(rnd *rand.Rand, layer []int32) int32 {
+
+	free := make([]int32, 0)
+	for i, v := range layer {
+
+		if v == puzzle.TileFloor {
+
+			free = append(free, int32(i))
+		}
+	}
+
+	if len(free) == 0 {
+
+		return -1
+	}
+	return free[rnd.Intn(len(free))]
+}
+
+func randomLayout(rnd *rand.Rand, p generatorParams) []int32 {
+
+	layer := make([]int32, p.width*p.height)
+
+	var border bool
+	for y := int32(0); y < p.height; y++ {
+
+		for x := int32(0); x < p.width; x++ {
+
+			// Borders are mostly walls, the gaps in
+			// them make blocks wrap around
+			border = x == 0 || y == 0 || x == p.width-1 || y == p.height-1
+			if (border && rnd.Float64() < 0.8) ||
+				(!border && rnd.Float64() < p.wallDensity) {
+
+				layer[y*p.width+x] = puzzle.TileWall
+			}
+		}
+	}
+
+	var i int32
+	for c := int32(0); c < p.colors; c++ {
+
+		i = randomFloorTile(rnd, layer)
+		if i < 0 {
+
+			return nil
+		}
+		layer[i] = puzzle.TileHoleFirst + c
+
+		for k := int32(0); k < p.blocksPerHole; k++ {
+
+			i = randomFloorTile(rnd, layer)
+			if i < 0 {
+
+				return nil
+			}
+			layer[i] = puzzle.TileBlockFirst + c
+		}
+	}
+
+	for k := int32(0); k < p.neutral; k++ {
+
+		i = randomFloorTile(rnd, layer)
+		if i < 0 {
+
+			return nil
+		}
+		layer[i] = puzzle.TileNeutralBlock
+	}
+
+	return layer
+}
+
+// Generates random layouts until one of them has a solution
+// with the wanted length, or gives up after maxAttempts
+func generateStage(rnd *rand.Rand, p generatorParams, maxAttempts int32) *generatedStage {
+
+	var layer []int32
+	var board *puzzle.Board
+	var sol *puzzle.Solution
+	var err error
+	var moves int32
+
+	for attempt := int32(0); attempt < maxAttempts; attempt++ {
+
+		layer = randomLayout(rnd, p)
+		if layer == nil {
+
+			continue
+		}
+
+		board, err = puzzle.NewBoard(p.width, p.height, layer)
+		if err != nil {
+
+			continue
+		}
+		board = board.WithRuleset(p.ruleset)
+
+		sol, err = puzzle.Solve(board, p.stateLimit)
+		if err != nil {
+
+			continue
+		}
+
+		moves = int32(len(sol.Moves))
+		if moves < p.minMoves || moves > p.maxMoves {
+
+			continue
+		}
+
+		return &generatedStage{
+			layer:      layer,
+			width:      p.width,
+			height:     p.height,
+			name:       randomName(rnd),
+			moves:      moves,
+			difficulty: computeDifficulty(moves),
+			ruleset:    p.ruleset,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+// Command generator creates random stages that are
+// guaranteed to have a solution and writes them as
+// TMX files the game can load
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jani-nykanen/blocked/src/puzzle"
+)
+
+func main() {
+
+	width := flag.Int("width", 7, "stage width")
+	height := flag.Int("height", 7, "stage height")
+	count := flag.Int("count", 10, "number of stages to generate")
+	colors := flag.Int("colors", 2, "number of hole colors, 1-4")
+	blocks := flag.Int("blocks", 2, "colored blocks per hole")
+	neutral := flag.Int("neutral", 2, "number of neutral blocks")
+	density := flag.Float64("walls", 0.2, "probability of an inner tile being a wall")
+	minMoves := flag.Int("min", 8, "minimum length of the solution")
+	maxMoves := flag.Int("max", 20, "maximum length of the solution")
+	limit := flag.Int("limit", 200000, "maximum number of states to visit per candidate")
+	attempts := flag.Int("attempts", 5000, "candidates to try per stage before giving up")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed")
+	out := flag.String("out", "generated", "output folder")
+	first := flag.Int("first", 1, "number of the first output file")
+	tileset := flag.String("tileset", "dev/editor_tiles.tsx", "path to the editor tileset")
+	rulesetName := flag.String("ruleset", "slide",
+		"how far the blocks move: "+strings.Join(puzzle.RulesetNames(), ", "))
+	flag.Parse()
+
+	if *colors < 1 || *colors > 4 {
+
+		fmt.Println("The number of colors must be between 1 and 4")
+		os.Exit(1)
+	}
+
+	ruleset, err := puzzle.ParseRuleset(*rulesetName)
+	if err != nil {
+
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = os.MkdirAll(*out, 0755)
+	if err != nil {
+
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Tiled wants the tileset path relative to the map
+	tilesetPath := *tileset
+	absOut, err1 := filepath.Abs(*out)
+	absTileset, err2 := filepath.Abs(*tileset)
+	if err1 == nil && err2 == nil {
+
+		rel, err := filepath.Rel(absOut, absTileset)
+		if err == nil {
+
+			tilesetPath = filepath.ToSlash(rel)
+		}
+	}
+
+	params := generatorParams{
+		width:         int32(*width),
+		height:        int32(*height),
+		colors:        int32(*colors),
+		blocksPerHole: int32(*blocks),
+		neutral:       int32(*neutral),
+		wallDensity:   *density,
+		minMoves:      int32(*minMoves),
+		maxMoves:      int32(*maxMoves),
+		stateLimit:    int32(*limit),
+		ruleset:       ruleset,
+	}
+	rnd := rand.New(rand.NewSource(*seed))
+
+	fmt.Printf("Seed: %d\n", *seed)
+
+	var st *generatedStage
+	var path string
+	for i := 0; i < *count; i++ {
+
+		st = generateStage(rnd, params, int32(*attempts))
+		if st == nil {
+
+			fmt.Println("Could not find a stage with the given parameters")
+			os.Exit(1)
+		}
+
+		path = *out + "/" + strconv.Itoa(*first+i) + ".tmx"
+		err = writeTMX(path, st, tilesetPath)
+		if err != nil {
+
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s: \"%s\", %d moves, difficulty %d\n",
+			path, st.name, st.moves, st.difficulty)
+	}
+}
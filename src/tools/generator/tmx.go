@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/jani-nykanen/blocked/src/puzzle"
+)
+
+func escapeAttribute(s string) string {
+
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+
+	return buf.String()
+}
+
+// Writes the stage in the same format Tiled
+// writes the hand-made stages
+func writeTMX(path string, st *generatedStage, tilesetPath string) error {
+
+	var buf bytes.Buffer
+
+	buf.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&buf, "<map version=\"1.2\" tiledversion=\"1.3.5\" "+
+		"orientation=\"orthogonal\" renderorder=\"right-down\" "+
+		"width=\"%d\" height=\"%d\" tilewidth=\"16\" tileheight=\"16\" "+
+		"infinite=\"0\" nextlayerid=\"2\" nextobjectid=\"1\">\n",
+		st.width, st.height)
+
+	buf.WriteString(" <properties>\n")
+	fmt.Fprintf(&buf, "  <property name=\"difficulty\" value=\"%d\"/>\n", st.difficulty)
+	fmt.Fprintf(&buf, "  <property name=\"moves\" value=\"%d\"/>\n", st.moves)
+	fmt.Fprintf(&buf, "  <property name=\"name\" value=\"%s\"/>\n", escapeAttribute(st.name))
+	if st.ruleset != puzzle.RulesetSlide {
+
+		fmt.Fprintf(&buf, "  <property name=\"ruleset\" value=\"%s\"/>\n", st.ruleset.String())
+	}
+	buf.WriteString(" </properties>\n")
+
+	fmt.Fprintf(&buf, " <tileset firstgid=\"1\" source=\"%s\"/>\n",
+		escapeAttribute(tilesetPath))
+
+	fmt.Fprintf(&buf, " <layer id=\"1\" name=\"Tile Layer 1\" width=\"%d\" height=\"%d\">\n",
+		st.width, st.height)
+	buf.WriteString("  <data encoding=\"csv\">\n")
+
+	for y := int32(0); y < st.height; y++ {
+
+		for x := int32(0); x < st.width; x++ {
+
+			buf.WriteString(strconv.Itoa(int(st.layer[y*st.width+x])))
+			if x < st.width-1 || y < st.height-1 {
+
+				buf.WriteString(",")
+			}
+		}
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("</data>\n")
+	buf.WriteString(" </layer>\n")
+	buf.WriteString("</map>\n")
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,366 @@
+// Command linter checks the stages for problems the
+// game would otherwise silently ignore
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jani-nykanen/blocked/src/core"
+	"github.com/jani-nykanen/blocked/src/puzzle"
+	"github.com/jani-nykanen/blocked/src/stagefile"
+)
+
+type linter struct {
+	problems int32
+	stats    bool
+}
+
+func (l *linter) report(path string, format string, args ...interface{}) {
+
+	fmt.Printf("%s: %s\n", path, fmt.Sprintf(format, args...))
+	l.problems++
+}
+
+func (l *linter) checkProperties(path string, tmap *core.Tilemap) {
+
+	if !tmap.HasProperty("name") {
+
+		l.report(path, "missing property \"name\"")
+
+	} else if strings.TrimSpace(tmap.GetProperty("name", "")) == "" {
+
+		l.report(path, "property \"name\" is empty")
+	}
+
+	moves, err := tmap.ParseNumericProperty("moves")
+	if err != nil {
+
+		l.report(path, "%s", err.Error())
+
+	} else if moves <= 0 {
+
+		l.report(path, "property \"moves\" must be positive, got %d", moves)
+	}
+
+	difficulty, err := tmap.ParseNumericProperty("difficulty")
+	if err != nil {
+
+		l.report(path, "%s", err.Error())
+
+	} else if difficulty < 1 || difficulty > 4 {
+
+		l.report(path, "property \"difficulty\" must be between 1 and 4, got %d",
+			difficulty)
+	}
+}
+
+func (l *linter) checkTiles(path string, tmap *core.Tilemap) {
+
+	// Only the first layer is used by the game
+	layer, err := tmap.CloneLayer(0)
+	if err != nil {
+
+		l.report(path, "%s", err.Error())
+		return
+	}
+
+	var holes, blocks, teleporters, paints [4]int32
+	var tid int32
+
+	counts := make(map[string]int32)
+
+	for y := int32(0); y < tmap.Height(); y++ {
+
+		for x := int32(0); x < tmap.Width(); x++ {
+
+			tid = layer[y*tmap.Width()+x]
+			if !puzzle.IsKnownTile(tid) {
+
+				l.report(path, "unknown tile %d at (%d, %d)", tid, x, y)
+				continue
+			}
+			counts[puzzle.TileName(tid)]++
+
+			if tid >= puzzle.TileHoleFirst && tid <= puzzle.TileHoleLast {
+
+				holes[tid-puzzle.TileHoleFirst]++
+
+			} else if tid >= puzzle.TileBlockFirst && tid <= puzzle.TileBlockLast {
+
+				blocks[tid-puzzle.TileBlockFirst]++
+
+			} else if puzzle.IsTeleporter(tid) {
+
+				teleporters[tid-puzzle.TileTeleporterFirst]++
+
+			} else if id := puzzle.PaintID(tid); id > 0 {
+
+				paints[id-1]++
+			}
+		}
+	}
+
+	for c := 0; c < 4; c++ {
+
+		// Paint tiles can give any block the color
+		if blocks[c] > 0 && holes[c] == 0 {
+
+			l.report(path, "%d block(s) of color %d, but no hole (tile %d) for them",
+				blocks[c], c+1, puzzle.TileHoleFirst+int32(c))
+
+		} else if paints[c] > 0 && holes[c] == 0 {
+
+			l.report(path, "paint of color %d, but no hole (tile %d) for it",
+				c+1, puzzle.TileHoleFirst+int32(c))
+
+		} else if holes[c] > 0 && blocks[c] == 0 && paints[c] == 0 {
+
+			l.report(path, "hole of color %d, but no block (tile %d) or paint for it",
+				c+1, puzzle.TileBlockFirst+int32(c))
+		}
+
+		if teleporters[c] != 0 && teleporters[c] != 2 {
+
+			l.report(path, "%d teleporter(s) with tile %d, they only work in pairs",
+				teleporters[c], puzzle.TileTeleporterFirst+int32(c))
+		}
+	}
+
+	if l.stats {
+
+		l.printStats(path, counts)
+	}
+}
+
+func (l *linter) printStats(path string, counts map[string]int32) {
+
+	names := make([]string, 0, len(counts))
+	for n := range counts {
+
+		if n != "floor" {
+
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, n := range names {
+
+		parts[i] = n + " " + strconv.Itoa(int(counts[n]))
+	}
+	fmt.Printf("%s: %s\n", path, strings.Join(parts, ", "))
+}
+
+func (l *linter) checkChannels(path string, board *puzzle.Board) {
+
+	plates := make(map[int32]int32)
+	gates := make(map[int32]int32)
+
+	var tid int32
+	for y := int32(0); y < board.Height(); y++ {
+
+		for x := int32(0); x < board.Width(); x++ {
+
+			tid = board.Tile(x, y)
+			if tid == puzzle.TilePlate {
+
+				plates[board.Channel(x, y)]++
+
+			} else if tid == puzzle.TileGateClosed || tid == puzzle.TileGateOpen {
+
+				gates[board.Channel(x, y)]++
+			}
+		}
+	}
+
+	for _, ch := range sortedKeys(gates) {
+
+		if plates[ch] == 0 {
+
+			l.report(path, "%d gate(s) on channel %d, but no plate to open them",
+				gates[ch], ch)
+		}
+	}
+	for _, ch := range sortedKeys(plates) {
+
+		if gates[ch] == 0 {
+
+			l.report(path, "%d plate(s) on channel %d, but no gates", plates[ch], ch)
+		}
+	}
+}
+
+// The holes with a capacity need enough blocks to fill them,
+// unless paint tiles can give the blocks the color
+func (l *linter) checkCapacity(path string, board *puzzle.Board) {
+
+	var need, have [4]int32
+	var tid int32
+
+	// Any paint tile may change the colors, even a neutral one
+	painted := false
+	for y := int32(0); y < board.Height(); y++ {
+
+		for x := int32(0); x < board.Width(); x++ {
+
+			tid = board.Tile(x, y)
+			if tid >= puzzle.TileHoleFirst && tid <= puzzle.TileHoleLast {
+
+				need[tid-puzzle.TileHoleFirst] += board.HoleCapacity(x, y)
+
+			} else if puzzle.PaintID(tid) >= 0 {
+
+				painted = true
+			}
+		}
+	}
+
+	for _, bl := range board.Blocks() {
+
+		if bl.ID > 0 {
+
+			have[bl.ID-1] += int32(len(bl.Footprint()))
+		}
+	}
+
+	for c := 0; c < 4; c++ {
+
+		if need[c] > have[c] && !painted {
+
+			l.report(path, "holes of color %d take %d block(s), but there are only %d",
+				c+1, need[c], have[c])
+		}
+	}
+}
+
+func sortedKeys(m map[int32]int32) []int32 {
+
+	keys := make([]int32, 0, len(m))
+	for k := range m {
+
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
+
+func (l *linter) checkStage(path string) {
+
+	tmap, err := core.ParseTMX(path)
+	if err != nil {
+
+		l.report(path, "%s", err.Error())
+		return
+	}
+
+	for _, w := range tmap.Warnings() {
+
+		l.report(path, "%s", w)
+	}
+
+	if tmap.Width() <= 0 || tmap.Height() <= 0 {
+
+		l.report(path, "invalid map size %dx%d", tmap.Width(), tmap.Height())
+		return
+	}
+
+	if tmap.LayerCount() == 0 {
+
+		l.report(path, "no tile layers")
+		return
+	}
+
+	for i := uint32(0); i < uint32(tmap.LayerCount()); i++ {
+
+		if tmap.LayerLength(i) != tmap.Width()*tmap.Height() {
+
+			l.report(path, "layer %d has %d tiles, but the map is %dx%d",
+				i, tmap.LayerLength(i), tmap.Width(), tmap.Height())
+		}
+	}
+
+	l.checkProperties(path, tmap)
+	l.checkTiles(path, tmap)
+
+	board, err := stagefile.NewBoard(tmap)
+	if err != nil {
+
+		l.report(path, "%s", err.Error())
+		return
+	}
+	l.checkChannels(path, board)
+	l.checkCapacity(path, board)
+}
+
+// The game loads stages 1, 2, 3... until a file is missing,
+// so the rest would never show up
+func (l *linter) checkNumbering(folder string, names []string) {
+
+	numbers := make([]int, 0)
+	for _, n := range names {
+
+		v, err := strconv.Atoi(strings.TrimSuffix(n, ".tmx"))
+		if err != nil {
+
+			l.report(filepath.Join(folder, n), "not loaded by the game, "+
+				"stage files must be named 1.tmx, 2.tmx...")
+			continue
+		}
+		numbers = append(numbers, v)
+	}
+	sort.Ints(numbers)
+
+	for i, v := range numbers {
+
+		if v != i+1 {
+
+			l.report(folder, "stage %d is missing, stages after it are never loaded", i+1)
+			break
+		}
+	}
+}
+
+func main() {
+
+	folder := flag.String("maps", "assets/maps", "folder that contains the stages")
+	stats := flag.Bool("stats", false, "print how many tiles of each kind the stages have")
+	flag.Parse()
+
+	files, err := ioutil.ReadDir(*folder)
+	if err != nil {
+
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	l := new(linter)
+	l.stats = *stats
+
+	names := make([]string, 0)
+	for _, f := range files {
+
+		if f.IsDir() || filepath.Ext(f.Name()) != ".tmx" {
+
+			continue
+		}
+		names = append(names, f.Name())
+		l.checkStage(filepath.Join(*folder, f.Name()))
+	}
+	l.checkNumbering(*folder, names)
+
+	if l.problems > 0 {
+
+		fmt.Printf("%d problem(s) found\n", l.problems)
+		os.Exit(1)
+	}
+	fmt.Printf("%d stage(s) checked, no problems found\n", len(names))
+}
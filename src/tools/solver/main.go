@@ -0,0 +1,147 @@
+// Command solver searches the optimal solution for the
+// stages and reports the ones whose "moves" property
+// differs from it
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jani-nykanen/blocked/src/puzzle"
+	"github.com/jani-nykanen/blocked/src/stagefile"
+)
+
+// A letter for each color, or the ID itself if
+// there are more colors than letters
+func groupName(id int32) string {
+
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	if id < 1 || id > int32(len(letters)) {
+
+		return strconv.Itoa(int(id))
+	}
+	return string(letters[id-1])
+}
+
+// In the group mode, each move is prefixed with
+// the name of the color
+func movesToString(sol *puzzle.Solution) string {
+
+	names := make([]string, len(sol.Moves))
+	for i, m := range sol.Moves {
+
+		names[i] = m.String()
+		if sol.Groups != nil {
+
+			names[i] = groupName(sol.Groups[i]) + ":" + names[i]
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// Returns false if the stage needs attention. If the
+// ruleset is given, the stage is solved with it instead
+// of its own, and the moves property is not checked
+func checkStage(folder string, index int32, limit int32, ruleset string) (bool, error) {
+
+	path := folder + "/" + strconv.Itoa(int(index)) + ".tmx"
+
+	tmap, board, err := stagefile.Load(path)
+	if err != nil {
+
+		return false, err
+	}
+
+	name := tmap.GetProperty("name", "null")
+	moves := tmap.GetNumericProperty("moves", 0)
+
+	if ruleset != "" {
+
+		r, err := puzzle.ParseRuleset(ruleset)
+		if err != nil {
+
+			return false, err
+		}
+		board = board.WithRuleset(r)
+	}
+
+	sol, err := puzzle.Solve(board, limit)
+	if err != nil {
+
+		fmt.Printf("Stage %d \"%s\": %s\n", index, name, err.Error())
+		return false, nil
+	}
+	optimum := int32(len(sol.Moves))
+
+	fmt.Printf("Stage %d \"%s\": %d moves (%s), %d states visited\n",
+		index, name, optimum, board.Ruleset().String(), sol.States)
+	fmt.Printf("    %s\n", movesToString(sol))
+
+	if ruleset == "" && optimum != moves {
+
+		fmt.Printf("    MISMATCH: the moves property is %d, the optimum is %d\n",
+			moves, optimum)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func main() {
+
+	folder := flag.String("maps", "assets/maps", "folder that contains the stages")
+	limit := flag.Int("limit", 2000000, "maximum number of states to visit per stage, 0 for no limit")
+	ruleset := flag.String("ruleset", "",
+		"solve with this ruleset instead of the stage's own: "+strings.Join(puzzle.RulesetNames(), ", "))
+	flag.Parse()
+
+	stages := make([]int32, 0)
+	for _, arg := range flag.Args() {
+
+		v, err := strconv.Atoi(arg)
+		if err != nil {
+
+			fmt.Printf("Invalid stage number: %s\n", arg)
+			os.Exit(1)
+		}
+		stages = append(stages, int32(v))
+	}
+
+	// If nothing is given, go through every stage
+	if len(stages) == 0 {
+
+		for i := int32(1); ; i++ {
+
+			_, err := os.Stat(*folder + "/" + strconv.Itoa(int(i)) + ".tmx")
+			if err != nil {
+
+				break
+			}
+			stages = append(stages, i)
+		}
+	}
+
+	flagged := 0
+	for _, index := range stages {
+
+		ok, err := checkStage(*folder, index, int32(*limit), *ruleset)
+		if err != nil {
+
+			fmt.Printf("Stage %d: %s\n", index, err.Error())
+		}
+		if !ok {
+
+			flagged++
+		}
+	}
+
+	if flagged > 0 {
+
+		fmt.Printf("%d stage(s) flagged\n", flagged)
+		os.Exit(1)
+	}
+}